@@ -0,0 +1,364 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Tree is a parse tree. Terminals are leaves.
+// Nonterminals added by the BNF expansion are not shown; their children
+// are children of the production they belong to. Options and repetitions
+// are shown as nodes with the Symbol "[]" or "{}", so that "[A] [A]"
+// deriving A from the first option differs from deriving it from the second.
+type Tree struct {
+	Symbol   string
+	Terminal bool
+	Kids     []*Tree
+}
+
+// String returns the tree as "name(kids)", with terminals as names and
+// options and repetitions as "[kids]" and "{kids}".
+func (t *Tree) String() string {
+	if t.Terminal {
+		return t.Symbol
+	}
+	var list []string
+	for _, kid := range t.Kids {
+		list = append(list, kid.String())
+	}
+	switch t.Symbol {
+	case "[]", "{}":
+		return t.Symbol[:1] + strings.Join(list, " ") + t.Symbol[1:]
+	}
+	return t.Symbol + "(" + strings.Join(list, " ") + ")"
+}
+
+// An Ambiguity is a sentence with two different parse trees.
+type Ambiguity struct {
+	Production string // the production that derives the sentence
+	Pos        int    // line of the production
+	Sentence   []string
+	Trees      [2]*Tree
+}
+
+func (a *Ambiguity) Error() string {
+	return fmt.Sprintf("%d: %s: ambiguous: %q has two parse trees", a.Pos, a.Production, strings.Join(a.Sentence, " "))
+}
+
+// AmbiguityOptions bound the search done by FindAmbiguity.
+type AmbiguityOptions struct {
+	MaxLength    int    // longest sentence to try
+	MaxSentences int    // most sentences to keep, 0 for no limit
+	Start        string // production to check, "" for every production
+}
+
+// ErrSearchLimit is returned by FindAmbiguity when it keeps more
+// sentences than allowed.
+var ErrSearchLimit = errors.New("search limit reached")
+
+// FindAmbiguity searches for the shortest sentence that a production can
+// derive in two different ways, trying every sentence up to the maximum
+// length. Sentences of the same length are checked in the order of the
+// productions. It returns nil if there is no such sentence.
+//
+// If the search keeps more sentences than allowed, it stops and returns
+// an error wrapping ErrSearchLimit that says how long the sentences that
+// were fully checked are.
+func FindAmbiguity(b *BNF, opts AmbiguityOptions) (*Ambiguity, error) {
+	c := b.Compiled()
+	s := &search{b: b, max: opts.MaxSentences}
+	s.minLen = minLengths(b)
+	s.table = make([][]*level, b.NumNonTerminals())
+
+	var check []int
+	if opts.Start != "" {
+		sym, ok := c.NonTerminalID(opts.Start)
+		if !ok || !c.Defined(sym) {
+			return nil, fmt.Errorf("no start production %q", opts.Start)
+		}
+		check = append(check, int(sym))
+	} else {
+		for i := 0; i < c.NumNonTerminals(); i++ {
+			if c.Defined(ebnf.Symbol(i)) {
+				check = append(check, i)
+			}
+		}
+	}
+
+	for length := 0; length <= opts.MaxLength; length++ {
+		if err := s.level(length); err != nil {
+			return nil, fmt.Errorf("%w: sentences up to length %d checked", err, length-1)
+		}
+		// prefer a production whose trees differ in the production itself
+		// over one that only contains an ambiguous production
+		var found *entry
+		at := 0
+		for _, n := range check {
+			for _, e := range s.table[n][length].list {
+				if len(e.derivations) < 2 {
+					continue
+				} else if b.Owner(s.divergence(n, e.derivations[0], e.derivations[1])) == ebnf.Symbol(n) {
+					return s.ambiguity(n, e), nil
+				} else if found == nil {
+					found, at = e, n
+				}
+			}
+		}
+		if found != nil {
+			return s.ambiguity(at, found), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *search) ambiguity(n int, e *entry) *Ambiguity {
+	c := s.b.Compiled()
+	a := &Ambiguity{Production: s.b.NonTerminal(n), Pos: c.Production(ebnf.Symbol(n)).Pos()}
+	for _, r := range e.sentence {
+		a.Sentence = append(a.Sentence, c.Terminal(ebnf.Symbol(r-runeBase)))
+	}
+	for i, d := range e.derivations[:2] {
+		a.Trees[i] = s.tree(n, d)[0]
+	}
+	return a
+}
+
+// divergence returns the nonterminal where two derivations of the same
+// sentence by the nonterminal n first differ.
+func (s *search) divergence(n int, d1, d2 *derivation) int {
+	for d1.rule == d2.rule {
+		next := -1
+		for i, kid := range d1.kids {
+			if kid == nil || kid == d2.kids[i] {
+				continue
+			} else if kid.sentence != d2.kids[i].sentence || next != -1 {
+				// the sentence is split differently, or more than one kid differs
+				return n
+			}
+			next = i
+		}
+		if next == -1 {
+			return n
+		}
+		n, d1, d2 = s.b.Rules[d1.rule].RHS[next].Index(), d1.kids[next], d2.kids[next]
+	}
+	return n
+}
+
+// runeBase is added to a terminal to store it as a rune in a sentence.
+// It keeps terminals out of the range of surrogate runes.
+const runeBase = 0x10000
+
+type search struct {
+	b      *BNF
+	max    int
+	count  int
+	minLen []int
+	// table[n][length] holds the sentences of that length derived by n,
+	// in the order they were found, with up to two derivations each
+	table [][]*level
+	ids   int
+}
+
+type level struct {
+	index map[string]*entry
+	list  []*entry
+}
+
+type entry struct {
+	sentence    string
+	derivations []*derivation
+	keys        map[string]bool // identities of the derivations
+}
+
+// A derivation is a rule and the derivations of the nonterminals in it.
+type derivation struct {
+	id       int
+	rule     int
+	sentence string
+	kids     []*derivation // nil for terminals
+}
+
+// level finds the sentences of the length derived by each nonterminal.
+// Rules with nullable symbols can derive sentences of the same length
+// from each other, so it repeats until nothing new is found.
+func (s *search) level(length int) error {
+	for n := range s.table {
+		s.table[n] = append(s.table[n], &level{index: make(map[string]*entry)})
+	}
+	type addition struct {
+		n        int
+		sentence string
+		d        *derivation
+		key      string
+	}
+	for {
+		var pending []addition
+		seen := make(map[string]bool) // nonterminal and key of each pending derivation
+		for r, rule := range s.b.Rules {
+			if s.minLen[rule.LHS] > length {
+				continue
+			}
+			s.combine(rule.RHS, length, "", nil, func(sentence string, kids []*derivation) {
+				e := s.table[rule.LHS][length].index[sentence]
+				if e != nil && len(e.derivations) >= 2 {
+					return
+				}
+				key := derivationKey(r, kids)
+				if e != nil && e.keys[key] {
+					return
+				}
+				if seen[strconv.Itoa(rule.LHS)+":"+key] {
+					return
+				}
+				seen[strconv.Itoa(rule.LHS)+":"+key] = true
+				pending = append(pending, addition{n: rule.LHS, sentence: sentence, d: &derivation{rule: r, sentence: sentence, kids: kids}, key: key})
+			})
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		for _, p := range pending {
+			lvl := s.table[p.n][length]
+			e := lvl.index[p.sentence]
+			if e == nil {
+				if s.max != 0 && s.count >= s.max {
+					return ErrSearchLimit
+				}
+				s.count++
+				e = &entry{sentence: p.sentence, keys: make(map[string]bool)}
+				lvl.index[p.sentence], lvl.list = e, append(lvl.list, e)
+			}
+			if len(e.derivations) < 2 && !e.keys[p.key] {
+				s.ids++
+				p.d.id = s.ids
+				e.keys[p.key], e.derivations = true, append(e.derivations, p.d)
+			}
+		}
+	}
+}
+
+// combine calls f for each way the symbols can derive a sentence of the length.
+func (s *search) combine(rhs []Sym, length int, sentence string, kids []*derivation, f func(string, []*derivation)) {
+	if len(rhs) == 0 {
+		if length == 0 {
+			f(sentence, append([]*derivation(nil), kids...))
+		}
+		return
+	}
+	rest := 0
+	for _, sym := range rhs[1:] {
+		if rest += s.minLength(sym); rest > length {
+			return
+		}
+	}
+	sym := rhs[0]
+	if sym.IsTerminal() {
+		if length-rest >= 1 {
+			s.combine(rhs[1:], length-1, sentence+string(rune(runeBase+sym.Index())), append(kids, nil), f)
+		}
+		return
+	}
+	n := sym.Index()
+	for k := s.minLen[n]; k <= length-rest && k < len(s.table[n]); k++ {
+		for _, e := range s.table[n][k].list {
+			for _, d := range e.derivations {
+				s.combine(rhs[1:], length-k, sentence+e.sentence, append(kids, d), f)
+			}
+		}
+	}
+}
+
+func (s *search) minLength(sym Sym) int {
+	if sym.IsTerminal() {
+		return 1
+	}
+	return s.minLen[sym.Index()]
+}
+
+// derivationKey returns the identity of a derivation.
+func derivationKey(rule int, kids []*derivation) string {
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(rule))
+	for _, kid := range kids {
+		sb.WriteByte(' ')
+		if kid != nil {
+			sb.WriteString(strconv.Itoa(kid.id))
+		}
+	}
+	return sb.String()
+}
+
+// tree returns the parse trees for a derivation of the nonterminal.
+// A new nonterminal for a nested alternative returns its children
+// instead of itself.
+func (s *search) tree(n int, d *derivation) []*Tree {
+	b := s.b
+	kids := s.kids(n, d)
+	if !b.IsNew(n) {
+		return []*Tree{{Symbol: b.NonTerminal(n), Kids: kids}}
+	}
+	switch b.Compiled().Node(b.Origin(n)).Kind {
+	case ebnf.OptionNode:
+		return []*Tree{{Symbol: "[]", Kids: kids}}
+	case ebnf.RepetitionNode:
+		return []*Tree{{Symbol: "{}", Kids: kids}}
+	}
+	return kids
+}
+
+// kids returns the children of the tree for a derivation of the
+// nonterminal. The iterations of a repetition are children of a single
+// tree.
+func (s *search) kids(n int, d *derivation) []*Tree {
+	b := s.b
+	var kids []*Tree
+	for i, sym := range b.Rules[d.rule].RHS {
+		switch {
+		case sym.IsTerminal():
+			kids = append(kids, &Tree{Symbol: b.Name(sym), Terminal: true})
+		case sym.Index() == n && b.IsNew(n):
+			kids = append(kids, s.kids(n, d.kids[i])...)
+		default:
+			kids = append(kids, s.tree(sym.Index(), d.kids[i])...)
+		}
+	}
+	return kids
+}
+
+// minLengths returns the length of the shortest sentence each
+// nonterminal derives, or math.MaxInt32 if it derives none.
+func minLengths(b *BNF) []int {
+	minLen := make([]int, b.NumNonTerminals())
+	for i := range minLen {
+		minLen[i] = math.MaxInt32
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range b.Rules {
+			length := 0
+			for _, sym := range rule.RHS {
+				if sym.IsTerminal() {
+					length++
+				} else {
+					length += minLen[sym.Index()]
+				}
+				if length >= math.MaxInt32 {
+					break
+				}
+			}
+			if length < minLen[rule.LHS] {
+				minLen[rule.LHS], changed = length, true
+			}
+		}
+	}
+	return minLen
+}
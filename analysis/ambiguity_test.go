@@ -0,0 +1,123 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"errors"
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBNF(t *testing.T) {
+	c, err := ebnf.Compile(parse(t, `
+		s = A [ B | C ] { s D } .
+		e = .`))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	b := NewBNF(c)
+	var got []string
+	for r := range b.Rules {
+		got = append(got, b.RuleString(r))
+	}
+	want := []string{
+		"s = A s:1 s:3",
+		"s:1 =",
+		"s:1 = s:2",
+		"s:2 = B",
+		"s:2 = C",
+		"s:3 =",
+		"s:3 = s:3 s D",
+		"e =",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if !b.IsNew(3) || b.IsNew(0) || b.Owner(4) != 0 {
+		t.Errorf("wrong IsNew or Owner")
+	}
+}
+
+func TestFindAmbiguity(t *testing.T) {
+	for i, tc := range []struct {
+		input    string
+		sentence string
+		trees    [2]string
+	}{
+		{`s = A { B } .`, "", [2]string{}},
+		{`e = e Plus e | N .`, "N Plus N Plus N", [2]string{
+			"e(e(e(N) Plus e(N)) Plus e(N))",
+			"e(e(N) Plus e(e(N) Plus e(N)))",
+		}},
+		{`s = If C Then s [ Else s ] | X .`, "If C Then If C Then X Else X", [2]string{
+			"s(If C Then s(If C Then s(X) [Else s(X)]) [])",
+			"s(If C Then s(If C Then s(X) []) [Else s(X)])",
+		}},
+		{`l = { x } .
+		  x = A | A A .`, "A A", [2]string{
+			"l({x(A) x(A)})",
+			"l({x(A A)})",
+		}},
+		// the trees differ only in which option or repetition derives A
+		{`s = [ A ] [ A ] .`, "A", [2]string{"s([A] [])", "s([] [A])"}},
+		{`s = { A } { A } .`, "A", [2]string{"s({A} {})", "s({} {A})"}},
+	} {
+		c, err := ebnf.Compile(parse(t, tc.input))
+		if err != nil {
+			t.Fatalf("%d: Compile failed: %v", i+1, err)
+		}
+		a, err := FindAmbiguity(NewBNF(c), AmbiguityOptions{MaxLength: 9})
+		if err != nil {
+			t.Errorf("%d: FindAmbiguity failed: %v", i+1, err)
+			continue
+		}
+		if a == nil {
+			if tc.sentence != "" {
+				t.Errorf("%d: want %q, got nil", i+1, tc.sentence)
+			}
+			continue
+		}
+		if got := strings.Join(a.Sentence, " "); got != tc.sentence {
+			t.Errorf("%d: want %q, got %q", i+1, tc.sentence, got)
+		}
+		// the order of the trees does not matter
+		got := [2]string{a.Trees[0].String(), a.Trees[1].String()}
+		if got[0] == got[1] {
+			t.Errorf("%d: both trees print as %q", i+1, got[0])
+		}
+		if got != tc.trees && got != [2]string{tc.trees[1], tc.trees[0]} {
+			t.Errorf("%d: want %q, got %q", i+1, tc.trees, got)
+		}
+	}
+}
+
+func TestFindAmbiguityLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ebnf.Compile(parse(t, string(input)))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	b := NewBNF(c)
+	a, err := FindAmbiguity(b, AmbiguityOptions{MaxLength: 4})
+	if err != nil {
+		t.Fatalf("FindAmbiguity failed: %v", err)
+	}
+	if a == nil {
+		t.Fatalf("want an ambiguity, got nil")
+	}
+	if got, want := a.Error(), `43: exp: ambiguous: "Minus Nil Plus Nil" has two parse trees`; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	_, err = FindAmbiguity(b, AmbiguityOptions{MaxLength: 4, MaxSentences: 100})
+	if !errors.Is(err, ErrSearchLimit) {
+		t.Errorf("want ErrSearchLimit, got %v", err)
+	}
+}
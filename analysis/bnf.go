@@ -0,0 +1,201 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"strings"
+)
+
+// A Sym is a symbol in a BNF grammar. Terminals are the Symbols of the
+// compiled grammar. Nonterminals are negative; see NT.
+type Sym int32
+
+// T returns the Sym for the terminal.
+func T(sym ebnf.Symbol) Sym { return Sym(sym) }
+
+// NT returns the Sym for the nonterminal with the index.
+func NT(n int) Sym { return Sym(^n) }
+
+// IsTerminal returns true if the symbol is a terminal.
+func (s Sym) IsTerminal() bool { return s >= 0 }
+
+// Index returns the terminal Symbol or the index of the nonterminal.
+func (s Sym) Index() int {
+	if s < 0 {
+		return int(^s)
+	}
+	return int(s)
+}
+
+// A Rule is a production of a BNF grammar.
+type Rule struct {
+	LHS  int         // index of the nonterminal
+	RHS  []Sym       // empty for an empty rule
+	Node ebnf.NodeID // the node the rule was made from, NoNode for an empty expression
+}
+
+// A BNF grammar is a compiled grammar with its options, repetitions, and
+// nested alternatives replaced by new nonterminals, so that every rule is
+// a plain sequence of symbols. Analyses that are defined for BNF, such as
+// LR parsing, work on it.
+//
+// The first nonterminals are those of the compiled grammar, with the same
+// numbers. The new ones follow, each named after its production, as in
+// "exp:1". For a node x,
+//
+//	[x] becomes n = | x .
+//	{x} becomes n = | n x .
+//	(x | y) becomes n = x | y .
+type BNF struct {
+	c      *ebnf.Compiled
+	Rules  []Rule
+	names  []string      // by nonterminal
+	origin []ebnf.NodeID // by nonterminal; the node a new nonterminal was made for
+	owner  []ebnf.Symbol // by nonterminal; the production it belongs to
+	rules  [][]int       // rule indexes by nonterminal
+}
+
+// NewBNF returns the BNF form of the compiled grammar.
+// Productions are expanded in source order, and the rules for a new
+// nonterminal follow the rules of the production it belongs to.
+func NewBNF(c *ebnf.Compiled) *BNF {
+	b := &BNF{c: c}
+	for i := 0; i < c.NumNonTerminals(); i++ {
+		b.names = append(b.names, c.NonTerminal(ebnf.Symbol(i)))
+		b.origin = append(b.origin, ebnf.NoNode)
+		b.owner = append(b.owner, ebnf.Symbol(i))
+		b.rules = append(b.rules, nil)
+	}
+	for i := 0; i < c.NumNonTerminals(); i++ {
+		sym := ebnf.Symbol(i)
+		if !c.Defined(sym) {
+			continue
+		}
+		root := c.Root(sym)
+		if root != ebnf.NoNode && c.Node(root).Kind == ebnf.AlternativeNode {
+			for _, kid := range c.Children(root) {
+				b.rule(sym, i, kid, kid)
+			}
+		} else {
+			b.rule(sym, i, root, root)
+		}
+	}
+	return b
+}
+
+// rule adds a rule for the nonterminal made from the node, with the
+// expansion of body as its right hand side. The rule is added before
+// the rules of any nonterminals created for the body.
+func (b *BNF) rule(owner ebnf.Symbol, lhs int, node, body ebnf.NodeID) int {
+	r := len(b.Rules)
+	b.rules[lhs] = append(b.rules[lhs], r)
+	b.Rules = append(b.Rules, Rule{LHS: lhs, Node: node})
+	if body != ebnf.NoNode {
+		rhs := b.expand(owner, body)
+		b.Rules[r].RHS = append(b.Rules[r].RHS, rhs...)
+	}
+	return r
+}
+
+// nonterminal adds a new nonterminal for the node.
+func (b *BNF) nonterminal(owner ebnf.Symbol, node ebnf.NodeID) int {
+	n := len(b.names)
+	count := 0
+	for _, o := range b.owner {
+		if o == owner {
+			count++
+		}
+	}
+	b.names = append(b.names, fmt.Sprintf("%s:%d", b.c.NonTerminal(owner), count))
+	b.origin = append(b.origin, node)
+	b.owner = append(b.owner, owner)
+	b.rules = append(b.rules, nil)
+	return n
+}
+
+// expand returns the symbols for the node as an element of a sequence.
+func (b *BNF) expand(owner ebnf.Symbol, id ebnf.NodeID) []Sym {
+	c := b.c
+	n := c.Node(id)
+	switch n.Kind {
+	case ebnf.TerminalNode:
+		return []Sym{T(n.Symbol)}
+	case ebnf.NonTerminalNode:
+		return []Sym{NT(int(n.Symbol))}
+	case ebnf.SequenceNode:
+		var list []Sym
+		for _, kid := range c.Children(id) {
+			list = append(list, b.expand(owner, kid)...)
+		}
+		return list
+	case ebnf.AlternativeNode:
+		nt := b.nonterminal(owner, id)
+		for _, kid := range c.Children(id) {
+			b.rule(owner, nt, kid, kid)
+		}
+		return []Sym{NT(nt)}
+	case ebnf.OptionNode:
+		nt := b.nonterminal(owner, id)
+		body := c.Children(id)[0]
+		b.rule(owner, nt, id, ebnf.NoNode)
+		b.rule(owner, nt, body, body)
+		return []Sym{NT(nt)}
+	case ebnf.RepetitionNode:
+		nt := b.nonterminal(owner, id)
+		body := c.Children(id)[0]
+		b.rule(owner, nt, id, ebnf.NoNode)
+		r := b.rule(owner, nt, body, ebnf.NoNode)
+		rhs := b.expand(owner, body)
+		b.Rules[r].RHS = append([]Sym{NT(nt)}, rhs...)
+		return []Sym{NT(nt)}
+	}
+	panic(fmt.Sprintf("assert(kind != %d)", n.Kind))
+}
+
+// Compiled returns the compiled grammar.
+func (b *BNF) Compiled() *ebnf.Compiled { return b.c }
+
+// NumNonTerminals returns the number of nonterminals, including the new ones.
+func (b *BNF) NumNonTerminals() int { return len(b.names) }
+
+// NonTerminal returns the name of the nonterminal.
+func (b *BNF) NonTerminal(n int) string { return b.names[n] }
+
+// IsNew returns true if the nonterminal was added for an option,
+// repetition, or nested alternative.
+func (b *BNF) IsNew(n int) bool { return b.origin[n] != ebnf.NoNode }
+
+// Owner returns the production the nonterminal belongs to.
+func (b *BNF) Owner(n int) ebnf.Symbol { return b.owner[n] }
+
+// Origin returns the node a new nonterminal was made for, or NoNode.
+func (b *BNF) Origin(n int) ebnf.NodeID { return b.origin[n] }
+
+// RulesFor returns the indexes of the rules for the nonterminal.
+// The slice is shared and must not be modified.
+func (b *BNF) RulesFor(n int) []int { return b.rules[n] }
+
+// Name returns the name of the symbol.
+func (b *BNF) Name(s Sym) string {
+	if s.IsTerminal() {
+		return b.c.Terminal(ebnf.Symbol(s))
+	}
+	return b.names[s.Index()]
+}
+
+// RuleString returns the rule as "lhs = rhs".
+func (b *BNF) RuleString(r int) string {
+	rule := b.Rules[r]
+	var sb strings.Builder
+	sb.WriteString(b.names[rule.LHS])
+	sb.WriteString(" =")
+	for _, s := range rule.RHS {
+		sb.WriteByte(' ')
+		sb.WriteString(b.Name(s))
+	}
+	return sb.String()
+}
@@ -0,0 +1,395 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Difference is a sentence that one of two grammars derives and the
+// other does not.
+type Difference struct {
+	Sentence []string
+	Starts   [2]string // start production of each grammar
+	Derived  [2]bool   // by grammar; true if it derives the sentence
+}
+
+func (d *Difference) Error() string {
+	by, not := 0, 1
+	if !d.Derived[0] {
+		by, not = 1, 0
+	}
+	which := [2]string{"first", "second"}
+	return fmt.Sprintf("%q is derived by %s in the %s grammar but not by %s in the %s", strings.Join(d.Sentence, " "), d.Starts[by], which[by], d.Starts[not], which[not])
+}
+
+// EquivalenceOptions bound the search done by FindDifference.
+type EquivalenceOptions struct {
+	MaxLength int       // longest sentence to try
+	MaxStates int       // most parser states to keep, 0 for no limit
+	Starts    [2]string // start production of each grammar
+}
+
+// FindDifference searches for the shortest sentence that is derived by
+// the start production of one grammar but not by the start production of
+// the other, trying every sentence up to the maximum length. Terminals
+// are matched by name. Sentences of the same length are tried in the
+// order of the names of their terminals. It returns nil if the grammars
+// derive the same sentences up to that length.
+//
+// Rather than listing the sentences of each grammar, it walks the
+// prefixes of the sentences of both at once. For each grammar a prefix
+// leads to a state, the sentential forms left to derive after it, and
+// prefixes that lead to states already searched are skipped. Terminals
+// that can replace each other anywhere in both grammars, such as the
+// operators of a binop production, are only tried once.
+//
+// If the search keeps more states than allowed, it stops and returns
+// an error wrapping ErrSearchLimit that says how long the sentences that
+// were fully checked are.
+func FindDifference(a, b *BNF, opts EquivalenceOptions) (*Difference, error) {
+	e := &equivalence{max: opts.MaxStates, index: make(map[string]int), failed: make(map[[2]int]bool)}
+	for _, g := range []*BNF{a, b} {
+		c := g.Compiled()
+		for i := 0; i < c.NumTerminals(); i++ {
+			e.index[c.Terminal(ebnf.Symbol(i))] = 0
+		}
+	}
+	for name := range e.index {
+		e.terminals = append(e.terminals, name)
+	}
+	sort.Strings(e.terminals)
+	for i, name := range e.terminals {
+		e.index[name] = i
+	}
+	for i, g := range []*BNF{a, b} {
+		c := g.Compiled()
+		sym, ok := c.NonTerminalID(opts.Starts[i])
+		if !ok || !c.Defined(sym) {
+			return nil, fmt.Errorf("no start production %q", opts.Starts[i])
+		}
+		e.langs[i] = e.language(g, int(sym))
+	}
+	e.classes()
+
+	for length := 0; length <= opts.MaxLength; length++ {
+		start := [2]*state{e.langs[0].start(length), e.langs[1].start(length)}
+		d := e.search(start, nil)
+		if e.err != nil {
+			return nil, fmt.Errorf("%w: sentences up to length %d checked", e.err, length-1)
+		} else if d != nil {
+			d.Starts = opts.Starts
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+type equivalence struct {
+	max       int
+	langs     [2]*language
+	terminals []string       // names of the terminals of both grammars, sorted
+	index     map[string]int // by name
+	rep       []int          // by terminal; the first terminal that can replace it
+	failed    map[[2]int]bool
+	err       error
+}
+
+// A language is a grammar with its empty rules removed, which derives
+// the sentences of the grammar except the empty one. Its symbols are
+// coded as runes so that a sentential form is a string: terminals, by
+// their index in the terminals of both grammars, and then nonterminals.
+type language struct {
+	e      *equivalence
+	nt     rune       // code of the first nonterminal
+	rules  [][]string // right hand sides by nonterminal
+	minLen []int      // by nonterminal
+	root   int        // the start nonterminal
+	empty  bool       // true if the start nonterminal derives the empty sentence
+	states map[string]*state
+	leads  map[[2]int][]string // by nonterminal and r; see lead
+}
+
+// A state is what is left to derive after a prefix of a sentence: the
+// sentential forms, each empty or starting with a terminal, that derive
+// the rest of the sentence in at most r more terminals.
+type state struct {
+	id     int
+	r      int
+	forms  []string // sorted
+	accept bool     // true if the prefix is a sentence
+	next   map[int]*state
+}
+
+// language returns the language of the nonterminal of the grammar.
+func (e *equivalence) language(b *BNF, root int) *language {
+	n := b.NumNonTerminals()
+	l := &language{e: e, nt: rune(runeBase + len(e.terminals)), rules: make([][]string, n), root: root, states: make(map[string]*state), leads: make(map[[2]int][]string)}
+	minLen := minLengths(b)
+	l.empty = minLen[root] == 0
+	code := func(s Sym) rune {
+		if s.IsTerminal() {
+			return rune(runeBase + e.index[b.Name(s)])
+		}
+		return l.nt + rune(s.Index())
+	}
+	seen := make(map[string]bool)
+	for _, rule := range b.Rules {
+		productive := true
+		for _, s := range rule.RHS {
+			if !s.IsTerminal() && minLen[s.Index()] == math.MaxInt32 {
+				productive = false
+			}
+		}
+		if !productive {
+			continue
+		}
+		// add the rule with each combination of its nullable symbols left out
+		var variants func(rhs []Sym, form string)
+		variants = func(rhs []Sym, form string) {
+			if len(rhs) == 0 {
+				key := strconv.Itoa(rule.LHS) + ":" + form
+				if form != "" && form != string(l.nt+rune(rule.LHS)) && !seen[key] {
+					seen[key] = true
+					l.rules[rule.LHS] = append(l.rules[rule.LHS], form)
+				}
+				return
+			}
+			if s := rhs[0]; !s.IsTerminal() && minLen[s.Index()] == 0 {
+				variants(rhs[1:], form)
+			}
+			variants(rhs[1:], form+string(code(rhs[0])))
+		}
+		variants(rule.RHS, "")
+	}
+	l.minLen = make([]int, n)
+	for i := range l.minLen {
+		l.minLen[i] = math.MaxInt32
+	}
+	for changed := true; changed; {
+		changed = false
+		for lhs, list := range l.rules {
+			for _, rhs := range list {
+				if length := l.length(rhs); length < l.minLen[lhs] {
+					l.minLen[lhs], changed = length, true
+				}
+			}
+		}
+	}
+	return l
+}
+
+// length returns the length of the shortest sentence the form derives.
+func (l *language) length(form string) int {
+	length := 0
+	for _, r := range form {
+		if r < l.nt {
+			length++
+		} else if length += l.minLen[r-l.nt]; length >= math.MaxInt32 {
+			return math.MaxInt32
+		}
+	}
+	return length
+}
+
+// start returns the state before the first terminal of a sentence of at
+// most r terminals.
+func (l *language) start(r int) *state {
+	seeds := []string{string(l.nt + rune(l.root))}
+	if l.empty {
+		seeds = append(seeds, "")
+	}
+	return l.state(seeds, r)
+}
+
+// state returns the state of the forms after expanding their leading
+// nonterminals, keeping the ones that can derive at most r terminals.
+func (l *language) state(seeds []string, r int) *state {
+	var forms []string
+	for _, f := range seeds {
+		x, size := utf8.DecodeRuneInString(f)
+		if f == "" || x < l.nt {
+			if l.length(f) <= r {
+				forms = append(forms, f)
+			}
+			continue
+		}
+		// only the leading nonterminal changes until a terminal leads
+		rest := f[size:]
+		if length := l.length(rest); length < math.MaxInt32 {
+			for _, lead := range l.lead(int(x-l.nt), r-length) {
+				forms = append(forms, lead+rest)
+			}
+		}
+	}
+	sort.Strings(forms)
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(r))
+	n := 0
+	for i, f := range forms {
+		if i > 0 && f == forms[i-1] {
+			continue
+		}
+		forms[n], n = f, n+1
+		sb.WriteByte(0) // so that no forms and the empty form differ
+		sb.WriteString(f)
+	}
+	forms = forms[:n]
+	key := sb.String()
+	if s, ok := l.states[key]; ok {
+		return s
+	}
+	s := &state{id: len(l.states), r: r, forms: forms, accept: len(forms) != 0 && forms[0] == ""}
+	l.states[key] = s
+	if e := l.e; e.max != 0 && len(e.langs[0].states)+len(e.langs[1].states) > e.max && e.err == nil {
+		e.err = ErrSearchLimit
+	}
+	return s
+}
+
+// lead returns the forms starting with a terminal that the nonterminal
+// derives by expanding its leading nonterminals, keeping the ones that
+// can derive at most r terminals.
+func (l *language) lead(n, r int) []string {
+	key := [2]int{n, r}
+	if list, ok := l.leads[key]; ok {
+		return list
+	}
+	var list []string
+	seen := make(map[string]bool)
+	worklist := []string{string(l.nt + rune(n))}
+	for len(worklist) != 0 {
+		f := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		x, size := utf8.DecodeRuneInString(f)
+		if x < l.nt {
+			list = append(list, f)
+			continue
+		}
+		for _, rhs := range l.rules[x-l.nt] {
+			if g := rhs + f[size:]; !seen[g] && l.length(g) <= r {
+				seen[g], worklist = true, append(worklist, g)
+			}
+		}
+	}
+	l.leads[key] = list
+	return list
+}
+
+// after returns the state after the terminal.
+func (l *language) after(s *state, t int) *state {
+	if next, ok := s.next[t]; ok {
+		return next
+	}
+	var seeds []string
+	if s.r > 0 {
+		code := string(rune(runeBase + t))
+		for _, f := range s.forms {
+			if strings.HasPrefix(f, code) {
+				seeds = append(seeds, f[len(code):])
+			}
+		}
+	}
+	next := l.state(seeds, s.r-1)
+	if s.next == nil {
+		s.next = make(map[int]*state)
+	}
+	s.next[t] = next
+	return next
+}
+
+// classes finds the terminals that can replace each other anywhere in
+// both grammars. Two terminals can if each rule with one of them in some
+// place has a twin rule with the other in that place.
+func (e *equivalence) classes() {
+	const hole = '*' // below the codes of the symbols
+	var sigs [2][]map[string]bool
+	for i, l := range e.langs {
+		sigs[i] = make([]map[string]bool, len(e.terminals))
+		for lhs, list := range l.rules {
+			for _, rhs := range list {
+				form := []rune(rhs)
+				for j, r := range form {
+					if r >= l.nt {
+						continue
+					}
+					t := int(r - runeBase)
+					form[j] = hole
+					if sigs[i][t] == nil {
+						sigs[i][t] = make(map[string]bool)
+					}
+					sigs[i][t][strconv.Itoa(lhs)+":"+string(form)] = true
+					form[j] = r
+				}
+			}
+		}
+	}
+	reps := make(map[string]int) // by signature in both grammars
+	e.rep = make([]int, len(e.terminals))
+	for t := range e.terminals {
+		var parts []string
+		for i := range sigs {
+			var list []string
+			for sig := range sigs[i][t] {
+				list = append(list, sig)
+			}
+			sort.Strings(list)
+			parts = append(parts, strings.Join(list, "\x00"))
+		}
+		key := strings.Join(parts, "\x01")
+		if rep, ok := reps[key]; ok {
+			e.rep[t] = rep
+		} else {
+			reps[key], e.rep[t] = t, t
+		}
+	}
+}
+
+// search returns the first sentence that starts with the prefix and is
+// derived by only one of the grammars, given the states after the prefix.
+func (e *equivalence) search(s [2]*state, prefix []int) *Difference {
+	if s[0].accept != s[1].accept {
+		d := &Difference{Derived: [2]bool{s[0].accept, s[1].accept}}
+		for _, t := range prefix {
+			d.Sentence = append(d.Sentence, e.terminals[t])
+		}
+		return d
+	}
+	key := [2]int{s[0].id, s[1].id}
+	if s[0].r == 0 || e.failed[key] {
+		return nil
+	}
+	// the terminals that continue the prefix in either grammar
+	seen := make(map[int]bool)
+	var next []int
+	for i := range s {
+		for _, f := range s[i].forms {
+			if f == "" {
+				continue
+			}
+			x, _ := utf8.DecodeRuneInString(f)
+			if t := e.rep[x-runeBase]; !seen[t] {
+				seen[t], next = true, append(next, t)
+			}
+		}
+	}
+	sort.Ints(next)
+	for _, t := range next {
+		after := [2]*state{e.langs[0].after(s[0], t), e.langs[1].after(s[1], t)}
+		if e.err != nil {
+			return nil
+		} else if d := e.search(after, append(prefix, t)); d != nil || e.err != nil {
+			return d
+		}
+	}
+	e.failed[key] = true
+	return nil
+}
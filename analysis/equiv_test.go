@@ -0,0 +1,111 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"errors"
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func compile(t *testing.T, src string) *BNF {
+	t.Helper()
+	c, err := ebnf.Compile(parse(t, src))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	return NewBNF(c)
+}
+
+func TestFindDifference(t *testing.T) {
+	for i, tc := range []struct {
+		a, b   string
+		starts [2]string
+		want   string
+	}{
+		{`s = A { B } .`, `s = A | s B .`, [2]string{"s", "s"}, ""},
+		{`e = e Plus e | N .`, `e = N { Plus N } .`, [2]string{"e", "e"}, ""},
+		{`a = X { Comma X } .`, `b = X [ Comma b ] .`, [2]string{"a", "b"}, ""},
+		{`s = A { B } .`, `s = A [ B ] .`, [2]string{"s", "s"},
+			`"A B B" is derived by s in the first grammar but not by s in the second`},
+		{`s = [ A ] .`, `s = A .`, [2]string{"s", "s"},
+			`"" is derived by s in the first grammar but not by s in the second`},
+		{`s = A .`, `s = A | B .`, [2]string{"s", "s"},
+			`"B" is derived by s in the second grammar but not by s in the first`},
+		// Minus and Plus can replace each other in the first grammar only
+		{`s = N { op N } .
+		  op = Plus | Minus .`, `s = N { Plus N } | N Minus N .`, [2]string{"s", "s"},
+			`"N Minus N Minus N" is derived by s in the first grammar but not by s in the second`},
+		{`s = N { op N } .
+		  op = Plus | Minus .`, `s = N { op N } .
+		  op = Plus | Minus | Star .`, [2]string{"s", "s"},
+			`"N Star N" is derived by s in the second grammar but not by s in the first`},
+		// unproductive alternatives derive nothing
+		{`s = A | B s .`, `s = A | B t . t = C t .`, [2]string{"s", "s"},
+			`"B A" is derived by s in the first grammar but not by s in the second`},
+	} {
+		d, err := FindDifference(compile(t, tc.a), compile(t, tc.b), EquivalenceOptions{MaxLength: 8, Starts: tc.starts})
+		if err != nil {
+			t.Errorf("%d: FindDifference failed: %v", i+1, err)
+			continue
+		}
+		got := ""
+		if d != nil {
+			got = d.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%d: want %q, got %q", i+1, tc.want, got)
+		}
+	}
+
+	_, err := FindDifference(compile(t, `s = A .`), compile(t, `s = A .`), EquivalenceOptions{Starts: [2]string{"s", "t"}})
+	if err == nil || err.Error() != `no start production "t"` {
+		t.Errorf("want no start production error, got %v", err)
+	}
+}
+
+func TestFindDifferenceLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lua := compile(t, string(input))
+	starts := [2]string{"chunk", "chunk"}
+	for i, tc := range []struct {
+		old, new string
+		want     string
+	}{
+		// inlining namelist does not change the language
+		{"parlist = namelist [Comma DotDotDot]", "parlist = Name {Comma Name} [Comma DotDotDot]", ""},
+		{"| Do block End", "| Do {stat} End",
+			`"Do Return End" is derived by chunk in the first grammar but not by chunk in the second`},
+		{"fieldlist = field {fieldsep field} [fieldsep] .", "fieldlist = field {fieldsep field} .",
+			`"Name LCurly DotDotDot Comma RCurly" is derived by chunk in the first grammar but not by chunk in the second`},
+	} {
+		if !strings.Contains(string(input), tc.old) {
+			t.Fatalf("%d: %q not found", i+1, tc.old)
+		}
+		changed := compile(t, strings.Replace(string(input), tc.old, tc.new, 1))
+		d, err := FindDifference(lua, changed, EquivalenceOptions{MaxLength: 8, Starts: starts})
+		if err != nil {
+			t.Errorf("%d: FindDifference failed: %v", i+1, err)
+			continue
+		}
+		got := ""
+		if d != nil {
+			got = d.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%d: want %q, got %q", i+1, tc.want, got)
+		}
+	}
+
+	_, err = FindDifference(lua, lua, EquivalenceOptions{MaxLength: 8, MaxStates: 100, Starts: starts})
+	if !errors.Is(err, ErrSearchLimit) {
+		t.Errorf("want ErrSearchLimit, got %v", err)
+	}
+}
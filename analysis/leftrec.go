@@ -0,0 +1,170 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/tokens"
+	"strings"
+)
+
+// A Step is a link in a left recursive chain: the expression of the
+// production From can start with a reference to To at Pos.
+type Step struct {
+	From, To ebnf.Symbol
+	Node     ebnf.NodeID // the reference to To
+	Pos      tokens.Position
+}
+
+// A Cycle is a chain of productions that start with the next one,
+// with the last one starting with the first. It is found by LeftRecursion.
+type Cycle struct {
+	c     *ebnf.Compiled
+	Steps []Step
+}
+
+// Direct returns true if the production starts with itself.
+func (x Cycle) Direct() bool { return len(x.Steps) == 1 }
+
+// Names returns the names of the productions in the chain,
+// starting and ending with the first one.
+func (x Cycle) Names() []string {
+	names := []string{x.c.NonTerminal(x.Steps[0].From)}
+	for _, step := range x.Steps {
+		names = append(names, x.c.NonTerminal(step.To))
+	}
+	return names
+}
+
+func (x Cycle) Error() string {
+	var sb strings.Builder
+	sb.WriteString(x.c.NonTerminal(x.Steps[0].From))
+	for _, step := range x.Steps {
+		fmt.Fprintf(&sb, " -> %s (%d:%d)", x.c.NonTerminal(step.To), step.Pos.Line, step.Pos.Col)
+	}
+	kind := "indirect"
+	if x.Direct() {
+		kind = "direct"
+	}
+	return fmt.Sprintf("%d: %s: %s left recursion: %s", x.Steps[0].Pos.Line, x.c.NonTerminal(x.Steps[0].From), kind, sb.String())
+}
+
+// LeftCorners calls f for each reference to a nonterminal that can start
+// a string derived from the node; that is, each reference that is only
+// preceded by expressions that can be empty.
+func (s *Sets) LeftCorners(id ebnf.NodeID, f func(ref ebnf.NodeID)) {
+	c := s.c
+	switch c.Node(id).Kind {
+	case ebnf.NonTerminalNode:
+		f(id)
+	case ebnf.AlternativeNode, ebnf.OptionNode, ebnf.RepetitionNode:
+		for _, kid := range c.Children(id) {
+			s.LeftCorners(kid, f)
+		}
+	case ebnf.SequenceNode:
+		for _, kid := range c.Children(id) {
+			s.LeftCorners(kid, f)
+			if !s.nodeNullable[kid] {
+				break
+			}
+		}
+	}
+}
+
+// leftCornerGraph returns, for each nonterminal, the first reference
+// to each nonterminal its production can start with.
+func (s *Sets) leftCornerGraph() [][]Step {
+	c := s.c
+	graph := make([][]Step, c.NumNonTerminals())
+	for i := range graph {
+		from := ebnf.Symbol(i)
+		if root := c.Root(from); root != ebnf.NoNode {
+			seen := make(map[ebnf.Symbol]bool)
+			s.LeftCorners(root, func(ref ebnf.NodeID) {
+				n := c.Node(ref)
+				if !seen[n.Symbol] {
+					seen[n.Symbol] = true
+					graph[from] = append(graph[from], Step{From: from, To: n.Symbol, Node: ref, Pos: n.Pos})
+				}
+			})
+		}
+	}
+	return graph
+}
+
+// LeftRecursion returns the left recursive cycles in the grammar.
+// A production is left recursive if it can start with itself, either
+// directly or through other productions, after expressions that can be
+// empty. A top-down parser loops forever on such productions.
+//
+// For each reference that starts a production, the shortest cycle back
+// to the production through that reference is reported. Each cycle is
+// reported once, starting with the production that comes first in the
+// source.
+func LeftRecursion(sets *Sets) []Cycle {
+	graph := sets.leftCornerGraph()
+	var cycles []Cycle
+	seen := make(map[string]bool)
+	for from := range graph {
+		for _, step := range graph[from] {
+			path := shortestPath(graph, step.To, ebnf.Symbol(from))
+			if path == nil && step.To != ebnf.Symbol(from) {
+				continue
+			}
+			steps := rotate(append([]Step{step}, path...))
+			var key []string
+			for _, step := range steps {
+				key = append(key, fmt.Sprint(step.From))
+			}
+			if k := strings.Join(key, " "); !seen[k] {
+				seen[k] = true
+				cycles = append(cycles, Cycle{c: sets.c, Steps: steps})
+			}
+		}
+	}
+	return cycles
+}
+
+// shortestPath returns the shortest path of steps from one nonterminal
+// to another. It returns nil if there is no path or they are the same.
+func shortestPath(graph [][]Step, from, to ebnf.Symbol) []Step {
+	if from == to {
+		return nil
+	}
+	// prev[sym] is the step used to reach sym
+	prev := make(map[ebnf.Symbol]Step)
+	queue := []ebnf.Symbol{from}
+	for len(queue) != 0 {
+		sym := queue[0]
+		queue = queue[1:]
+		for _, step := range graph[sym] {
+			if _, ok := prev[step.To]; ok || step.To == from {
+				continue
+			}
+			prev[step.To] = step
+			if step.To == to {
+				var path []Step
+				for at := to; at != from; at = prev[at].From {
+					path = append([]Step{prev[at]}, path...)
+				}
+				return path
+			}
+			queue = append(queue, step.To)
+		}
+	}
+	return nil
+}
+
+// rotate returns the cycle starting with the step from the lowest symbol.
+func rotate(steps []Step) []Step {
+	low := 0
+	for i, step := range steps {
+		if step.From < steps[low].From {
+			low = i
+		}
+	}
+	return append(steps[low:len(steps):len(steps)], steps[:low]...)
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLeftRecursion(t *testing.T) {
+	for i, tc := range []struct {
+		input string
+		want  []string
+	}{
+		{`s = A s | B .`, nil},
+		{`s = s A | B .`, []string{"1: s: direct left recursion: s -> s (1:5)"}},
+		{`s = [ A ] { B } s C | D .`, []string{"1: s: direct left recursion: s -> s (1:17)"}},
+		{`s = t A .
+		  t = [ B ] u .
+		  u = s | C .`, []string{"1: s: indirect left recursion: s -> t (1:5) -> u (2:15) -> s (3:9)"}},
+		{`s = a | b .
+		  a = s A .
+		  b = s B .`, []string{
+			"1: s: indirect left recursion: s -> a (1:5) -> s (2:9)",
+			"1: s: indirect left recursion: s -> b (1:9) -> s (3:9)",
+		}},
+		// C is not nullable
+		{`s = t s .
+		  t = C .`, nil},
+	} {
+		sets, err := Analyze(parse(t, tc.input), "s")
+		if err != nil {
+			t.Fatalf("%d: Analyze failed: %v", i+1, err)
+		}
+		var got []string
+		for _, c := range LeftRecursion(sets) {
+			got = append(got, c.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%d: want %q, got %q", i+1, tc.want, got)
+		}
+	}
+}
+
+func TestLeftRecursionLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets, err := Analyze(parse(t, string(input)), "chunk")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	var got []string
+	for _, c := range LeftRecursion(sets) {
+		got = append(got, strings.Join(c.Names(), " "))
+	}
+	want := []string{
+		"var prefixexp var",
+		"exp exp",
+		"prefixexp functioncall prefixexp",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/tokens"
+	"strings"
+)
+
+// A Conflict is a place where a parser with one token of lookahead
+// can not decide what to do. It is found by LL1.
+type Conflict struct {
+	Production string          // name of the production
+	Node       ebnf.NodeID     // the Alternative, Option, or Repetition
+	Kind       ebnf.NodeKind   // kind of the node
+	Pos        tokens.Position // position of the node
+	Expr       ebnf.Expression // the node's expression
+	// Alternatives are the indexes of the two alternatives of an Alternative
+	// that conflict. It is nil for an Option or Repetition.
+	Alternatives []int
+	// Terminals are the terminals that do not decide between the choices.
+	// "$" is the end of input.
+	Terminals []string
+	// Empty is set if both alternatives can be empty, or if the body of
+	// an Option or Repetition can be empty.
+	Empty bool
+}
+
+func (c Conflict) Error() string {
+	var where, what string
+	if c.Kind == ebnf.AlternativeNode {
+		where = fmt.Sprintf("alternatives %d and %d", c.Alternatives[0]+1, c.Alternatives[1]+1)
+		if c.Empty {
+			what = "can both be empty"
+		}
+	} else {
+		where = fmt.Sprintf("%s %s", c.Kind, ebnf.ExprString(c.Expr))
+		if c.Empty {
+			what = "can be empty"
+		}
+	}
+	if len(c.Terminals) != 0 {
+		if what != "" {
+			what += " and "
+		}
+		what += fmt.Sprintf("conflict on %s", strings.Join(c.Terminals, " "))
+	}
+	return fmt.Sprintf("%d:%d: %s: %s %s", c.Pos.Line, c.Pos.Col, c.Production, where, what)
+}
+
+// LL1 returns the conflicts that keep the grammar from being parsed by
+// recursive descent with one token of lookahead, using Wirth's rules:
+//
+//   - the alternatives of an Alternative must start with different
+//     terminals. An alternative that can be empty also starts with
+//     the terminals that can follow the Alternative.
+//   - the body of an Option or Repetition must not start with a terminal
+//     that can follow the Option or Repetition, and must not be empty.
+//
+// Conflicts are returned in source order.
+func LL1(sets *Sets) []Conflict {
+	c := sets.c
+	var conflicts []Conflict
+	var walk func(prod string, id ebnf.NodeID)
+	walk = func(prod string, id ebnf.NodeID) {
+		n := c.Node(id)
+		conflict := Conflict{Production: prod, Node: id, Kind: n.Kind, Pos: n.Pos, Expr: c.Expr(id)}
+		switch n.Kind {
+		case ebnf.AlternativeNode:
+			kids := c.Children(id)
+			director := make([]ebnf.Bitset, len(kids))
+			for i, kid := range kids {
+				director[i] = sets.nodeFirst[kid].Clone()
+				if sets.nodeNullable[kid] {
+					director[i].Union(sets.nodeFollow[id])
+				}
+			}
+			for i := range kids {
+				for j := i + 1; j < len(kids); j++ {
+					empty := sets.nodeNullable[kids[i]] && sets.nodeNullable[kids[j]]
+					if overlap := director[i].Intersection(director[j]); empty || !overlap.IsEmpty() {
+						conflict := conflict
+						conflict.Alternatives = []int{i, j}
+						conflict.Terminals = sets.Names(overlap)
+						conflict.Empty = empty
+						conflicts = append(conflicts, conflict)
+					}
+				}
+			}
+		case ebnf.OptionNode, ebnf.RepetitionNode:
+			body := c.Children(id)[0]
+			overlap := sets.nodeFirst[body].Intersection(sets.nodeFollow[id])
+			if empty := sets.nodeNullable[body]; empty || !overlap.IsEmpty() {
+				conflict.Terminals = sets.Names(overlap)
+				conflict.Empty = empty
+				conflicts = append(conflicts, conflict)
+			}
+		}
+		for _, kid := range c.Children(id) {
+			walk(prod, kid)
+		}
+	}
+	for i := 0; i < c.NumNonTerminals(); i++ {
+		if root := c.Root(ebnf.Symbol(i)); root != ebnf.NoNode {
+			walk(c.NonTerminal(ebnf.Symbol(i)), root)
+		}
+	}
+	return conflicts
+}
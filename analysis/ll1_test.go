@@ -0,0 +1,71 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLL1(t *testing.T) {
+	for i, tc := range []struct {
+		input string
+		want  []string
+	}{
+		{`s = A B | C .`, nil},
+		{`s = A B | A C .`, []string{"1:5: s: alternatives 1 and 2 conflict on A"}},
+		{`s = x | y .
+		  x = [ A ] .
+		  y = A B .`, []string{"1:5: s: alternatives 1 and 2 conflict on A"}},
+		{`s = [ A ] A .`, []string{"1:5: s: option [A] conflict on A"}},
+		{`s = { A B } A .`, []string{"1:5: s: repetition {A B} conflict on A"}},
+		{`s = { [ A ] } B .`, []string{"1:5: s: repetition {[A]} can be empty", "1:7: s: option [A] conflict on A"}},
+		{`s = [ x ] .
+		  x = [ A ] .`, []string{"1:5: s: option [x] can be empty"}},
+		{`s = x | y .
+		  x = .
+		  y = [ A ] .`, []string{"1:5: s: alternatives 1 and 2 can both be empty and conflict on $"}},
+	} {
+		sets, err := Analyze(parse(t, tc.input), "s")
+		if err != nil {
+			t.Fatalf("%d: Analyze failed: %v", i+1, err)
+		}
+		var got []string
+		for _, c := range LL1(sets) {
+			got = append(got, c.Error())
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%d: want %q, got %q", i+1, tc.want, got)
+			continue
+		}
+		for j := range got {
+			if got[j] != tc.want[j] {
+				t.Errorf("%d: want %q, got %q", i+1, tc.want[j], got[j])
+			}
+		}
+	}
+}
+
+func TestLL1Lua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets, err := Analyze(parse(t, string(input)), "chunk")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	// the Lua grammar is left recursive, so exp must have conflicts
+	want := "43:7: exp: alternatives 1 and 10 conflict on Nil"
+	found := false
+	for _, c := range LL1(sets) {
+		if c.Error() == want {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("exp: want conflict %q", want)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+)
+
+// A Recursion is the way a production refers to itself.
+type Recursion int
+
+const (
+	NotRecursive               Recursion = iota
+	DirectRecursion                      // the production references itself and no other production leads back to it
+	IndirectRecursion                    // the production is in a cycle with other productions
+	DirectAndIndirectRecursion           // the production references itself and is in a cycle with other productions
+)
+
+func (r Recursion) String() string {
+	switch r {
+	case NotRecursive:
+		return "none"
+	case DirectRecursion:
+		return "direct"
+	case IndirectRecursion:
+		return "indirect"
+	case DirectAndIndirectRecursion:
+		return "direct+indirect"
+	}
+	panic(fmt.Sprintf("assert(recursion != %d)", r))
+}
+
+// MarshalText writes the recursion as its String, so that it reads
+// well in JSON.
+func (r Recursion) MarshalText() ([]byte, error) { return []byte(r.String()), nil }
+
+// ProductionMetrics are the metrics of a single production.
+type ProductionMetrics struct {
+	Name         string    `json:"name"`
+	Line         int       `json:"line"`
+	Alternatives int       `json:"alternatives"` // top level alternatives, 1 for an empty production
+	Depth        int       `json:"depth"`        // deepest nesting of groups, options, and repetitions
+	FanIn        int       `json:"fanIn"`        // other productions that reference it
+	FanOut       int       `json:"fanOut"`       // other nonterminals it references
+	Recursion    Recursion `json:"recursion"`
+}
+
+// Metrics are measures of the size and complexity of a grammar.
+type Metrics struct {
+	Productions     int `json:"productions"`
+	Terminals       int `json:"terminals"`    // distinct terminals referenced
+	NonTerminals    int `json:"nonterminals"` // distinct nonterminals defined or referenced
+	Alternatives    int `json:"alternatives"` // top level alternatives of all productions
+	MaxAlternatives int `json:"maxAlternatives"`
+	MaxDepth        int `json:"maxDepth"`
+	Direct          int `json:"directRecursive"`   // productions that reference themselves
+	Indirect        int `json:"indirectRecursive"` // productions in a cycle with other productions
+	// LongestChain is the longest chain of references between the
+	// components of the grammar, the groups of productions that reach
+	// each other, with each component counted once. It lists the
+	// production the chain enters each component by; the next one is
+	// referenced by that production or by another in its component.
+	LongestChain  []string            `json:"longestChain"`
+	PerProduction []ProductionMetrics `json:"perProduction"` // in source order
+}
+
+// Measure returns the metrics of the grammar. References to undefined
+// productions count as nonterminals but are not followed.
+func Measure(grammar ebnf.Grammar) *Metrics {
+	m := &Metrics{Productions: len(grammar), LongestChain: []string{}, PerProduction: []ProductionMetrics{}}
+	g := ebnf.NewGraph(grammar)
+	components := g.Components()
+	terminals, nonterminals := make(map[string]bool), make(map[string]bool)
+	for _, name := range g.Productions() {
+		prod := grammar[name]
+		nonterminals[name] = true
+		pm := ProductionMetrics{Name: name, Line: prod.Pos(), Alternatives: 1, Depth: depth(prod.Expr)}
+		if x, ok := prod.Expr.(ebnf.Alternative); ok {
+			pm.Alternatives = len(x)
+		}
+		ebnf.Apply(prod.Expr, func(c *ebnf.Cursor) bool {
+			if x, ok := c.Node().(*ebnf.Literal); ok {
+				terminals[x.String()] = true
+			}
+			return true
+		}, nil)
+		seen := map[string]bool{name: true}
+		for _, ref := range g.References(name) {
+			nonterminals[ref.To] = true
+			if !seen[ref.To] {
+				seen[ref.To] = true
+				pm.FanOut++
+			}
+		}
+		seen = map[string]bool{name: true}
+		for _, ref := range g.ReferencedBy(name) {
+			if !seen[ref.From] {
+				seen[ref.From] = true
+				pm.FanIn++
+			}
+		}
+		direct := false
+		for _, ref := range g.References(name) {
+			direct = direct || ref.To == name
+		}
+		indirect := len(components[g.Component(name)]) > 1
+		switch {
+		case direct && indirect:
+			pm.Recursion = DirectAndIndirectRecursion
+		case direct:
+			pm.Recursion = DirectRecursion
+		case indirect:
+			pm.Recursion = IndirectRecursion
+		}
+		if direct {
+			m.Direct++
+		}
+		if indirect {
+			m.Indirect++
+		}
+		m.Alternatives += pm.Alternatives
+		if pm.Alternatives > m.MaxAlternatives {
+			m.MaxAlternatives = pm.Alternatives
+		}
+		if pm.Depth > m.MaxDepth {
+			m.MaxDepth = pm.Depth
+		}
+		m.PerProduction = append(m.PerProduction, pm)
+	}
+	m.Terminals, m.NonTerminals = len(terminals), len(nonterminals)
+
+	// the longest chain through the components; they come in reverse
+	// topological order, so a component comes after the ones it references
+	longest := make([]int, len(components)) // by component, the length of the longest chain from it
+	next := make([]string, len(components)) // by component, the production the chain enters the next one by
+	for c, names := range components {
+		longest[c] = 1
+		for _, name := range names {
+			for _, to := range g.Uses(name) {
+				if d := g.Component(to); d != c && longest[d]+1 > longest[c] {
+					longest[c], next[c] = longest[d]+1, to
+				}
+			}
+		}
+	}
+	start := ""
+	for _, name := range g.Productions() {
+		if c := g.Component(name); start == "" || longest[c] > longest[g.Component(start)] {
+			start = name
+		}
+	}
+	for name := start; name != ""; name = next[g.Component(name)] {
+		m.LongestChain = append(m.LongestChain, name)
+	}
+	return m
+}
+
+// depth returns the deepest nesting of groups, options, and repetitions
+// in the expression.
+func depth(x ebnf.Expression) int {
+	deepest := 0
+	switch x := x.(type) {
+	case ebnf.Alternative:
+		for _, e := range x {
+			if d := depth(e); d > deepest {
+				deepest = d
+			}
+		}
+	case ebnf.Sequence:
+		for _, e := range x {
+			if d := depth(e); d > deepest {
+				deepest = d
+			}
+		}
+	case *ebnf.Group:
+		deepest = depth(x.Body) + 1
+	case *ebnf.Option:
+		deepest = depth(x.Body) + 1
+	case *ebnf.Repetition:
+		deepest = depth(x.Body) + 1
+	}
+	return deepest
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	m := Measure(parse(t, `
+		s = a { b [ C ( D | E ) ] } | F .
+		a = a C | b .
+		b = [ G s ] .
+		c = H .`))
+	for _, tc := range []struct {
+		name      string
+		got, want any
+	}{
+		{"productions", m.Productions, 4},
+		{"terminals", m.Terminals, 6},
+		{"nonterminals", m.NonTerminals, 4},
+		{"alternatives", m.Alternatives, 6},
+		{"max alternatives", m.MaxAlternatives, 2},
+		{"max depth", m.MaxDepth, 3},
+		{"direct", m.Direct, 1},
+		{"indirect", m.Indirect, 3},
+		{"longest chain", strings.Join(m.LongestChain, " "), "s"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+	var got []string
+	for _, pm := range m.PerProduction {
+		got = append(got, fmt.Sprintf("%d %s %d %d %d %d %s", pm.Line, pm.Name, pm.Alternatives, pm.Depth, pm.FanIn, pm.FanOut, pm.Recursion))
+	}
+	want := []string{
+		"2 s 2 3 1 2 indirect",
+		"3 a 2 0 1 1 direct+indirect",
+		"4 b 1 1 2 1 indirect",
+		"5 c 1 0 0 0 none",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	m = Measure(parse(t, `
+		s = t .
+		t = u | t A .
+		u = B .`))
+	if got := strings.Join(m.LongestChain, " "); got != "s t u" {
+		t.Errorf("longest chain: want %q, got %q", "s t u", got)
+	}
+	if m.Direct != 1 || m.PerProduction[1].Recursion != DirectRecursion {
+		t.Errorf("want t to be directly recursive, got %v", m.PerProduction[1].Recursion)
+	}
+}
+
+func TestMeasureLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Measure(parse(t, string(input)))
+	if m.Productions != 25 || m.NonTerminals != 25 {
+		t.Errorf("want 25 productions and nonterminals, got %d and %d", m.Productions, m.NonTerminals)
+	}
+	if m.PerProduction[0].Name != "chunk" || m.PerProduction[0].FanIn != 0 {
+		t.Errorf("want chunk first with no fan-in, got %+v", m.PerProduction[0])
+	}
+	// exp = exp binop exp is in the cycle of the statements and expressions
+	if m.Direct != 1 || m.Indirect != 15 {
+		t.Errorf("want 1 direct and 15 indirect, got %d and %d", m.Direct, m.Indirect)
+	}
+	if got, want := strings.Join(m.LongestChain, " "), "chunk block attnamelist attrib"; got != want {
+		t.Errorf("longest chain: want %q, got %q", want, got)
+	}
+}
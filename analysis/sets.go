@@ -0,0 +1,221 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+// Package analysis computes properties of EBNF grammars that parsers
+// and grammar checks depend on, starting with the nullable, FIRST, and
+// FOLLOW sets used by Wirth's method.
+//
+// The analyses work on the compiled form of a grammar (see ebnf.Compile)
+// and handle options and repetitions directly, without first rewriting
+// the grammar into BNF.
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+)
+
+// Sets holds the nullable, FIRST, and FOLLOW sets for every nonterminal
+// and every node of a compiled grammar.
+//
+// The sets are Bitsets of terminal symbols. FOLLOW sets may also contain
+// EOF, the end of input, which follows the start symbols.
+// The sets returned by the methods are shared and must not be modified.
+type Sets struct {
+	c      *ebnf.Compiled
+	starts []ebnf.Symbol
+
+	nullable []bool        // by nonterminal
+	first    []ebnf.Bitset // by nonterminal
+	follow   []ebnf.Bitset // by nonterminal
+
+	nodeNullable []bool        // by node
+	nodeFirst    []ebnf.Bitset // by node
+	nodeFollow   []ebnf.Bitset // by node
+}
+
+// Analyze compiles the grammar and computes its sets.
+// The start symbols are followed by the end of input.
+func Analyze(grammar ebnf.Grammar, starts ...string) (*Sets, error) {
+	c, err := ebnf.Compile(grammar)
+	if err != nil {
+		return nil, err
+	}
+	var syms []ebnf.Symbol
+	for _, start := range starts {
+		sym, ok := c.NonTerminalID(start)
+		if !ok || !c.Defined(sym) {
+			return nil, fmt.Errorf("no start production %q", start)
+		}
+		syms = append(syms, sym)
+	}
+	return NewSets(c, syms...), nil
+}
+
+// NewSets computes the sets for the compiled grammar.
+// The start symbols are followed by the end of input.
+func NewSets(c *ebnf.Compiled, starts ...ebnf.Symbol) *Sets {
+	s := &Sets{c: c, starts: starts}
+	size := s.EOF() + 1
+	s.nullable = make([]bool, c.NumNonTerminals())
+	s.first = make([]ebnf.Bitset, c.NumNonTerminals())
+	s.follow = make([]ebnf.Bitset, c.NumNonTerminals())
+	for i := range s.first {
+		s.first[i], s.follow[i] = ebnf.NewBitset(size), ebnf.NewBitset(size)
+	}
+	s.nodeNullable = make([]bool, c.NumNodes())
+	s.nodeFirst = make([]ebnf.Bitset, c.NumNodes())
+	s.nodeFollow = make([]ebnf.Bitset, c.NumNodes())
+	for i := range s.nodeFirst {
+		s.nodeFirst[i], s.nodeFollow[i] = ebnf.NewBitset(size), ebnf.NewBitset(size)
+	}
+	s.computeFirst()
+	s.computeFollow()
+	return s
+}
+
+// computeFirst computes nullable and FIRST.
+// Children always have higher IDs than their parents, so visiting the
+// nodes in reverse order computes the children first. The nonterminals
+// are updated after each pass until nothing changes.
+func (s *Sets) computeFirst() {
+	c := s.c
+	for changed := true; changed; {
+		changed = false
+		for id := ebnf.NodeID(c.NumNodes() - 1); id >= 0; id-- {
+			n, first := c.Node(id), s.nodeFirst[id]
+			switch n.Kind {
+			case ebnf.TerminalNode:
+				first.Set(int(n.Symbol))
+			case ebnf.NonTerminalNode:
+				s.nodeNullable[id] = s.nullable[n.Symbol]
+				first.Union(s.first[n.Symbol])
+			case ebnf.AlternativeNode:
+				for _, kid := range c.Children(id) {
+					s.nodeNullable[id] = s.nodeNullable[id] || s.nodeNullable[kid]
+					first.Union(s.nodeFirst[kid])
+				}
+			case ebnf.SequenceNode:
+				nullable := true
+				for _, kid := range c.Children(id) {
+					first.Union(s.nodeFirst[kid])
+					if nullable = s.nodeNullable[kid]; !nullable {
+						break
+					}
+				}
+				s.nodeNullable[id] = nullable
+			case ebnf.OptionNode, ebnf.RepetitionNode:
+				s.nodeNullable[id] = true
+				first.Union(s.nodeFirst[c.Children(id)[0]])
+			}
+		}
+		for i := range s.first {
+			sym := ebnf.Symbol(i)
+			if !c.Defined(sym) {
+				continue
+			}
+			nullable := true
+			if root := c.Root(sym); root != ebnf.NoNode {
+				nullable = s.nodeNullable[root]
+				if s.first[sym].Union(s.nodeFirst[root]) {
+					changed = true
+				}
+			}
+			if nullable && !s.nullable[sym] {
+				s.nullable[sym], changed = true, true
+			}
+		}
+	}
+}
+
+// computeFollow computes FOLLOW.
+// Parents always have lower IDs than their children, so visiting the
+// nodes in order passes the FOLLOW set of a node down to its children.
+func (s *Sets) computeFollow() {
+	c := s.c
+	for _, start := range s.starts {
+		s.follow[start].Set(s.EOF())
+	}
+	acc := ebnf.NewBitset(s.EOF() + 1)
+	for changed := true; changed; {
+		changed = false
+		for i := range s.follow {
+			if root := c.Root(ebnf.Symbol(i)); root != ebnf.NoNode {
+				s.nodeFollow[root].Union(s.follow[i])
+			}
+		}
+		for id := ebnf.NodeID(0); int(id) < c.NumNodes(); id++ {
+			n, follow := c.Node(id), s.nodeFollow[id]
+			switch n.Kind {
+			case ebnf.NonTerminalNode:
+				if s.follow[n.Symbol].Union(follow) {
+					changed = true
+				}
+			case ebnf.AlternativeNode, ebnf.OptionNode:
+				for _, kid := range c.Children(id) {
+					s.nodeFollow[kid].Union(follow)
+				}
+			case ebnf.RepetitionNode:
+				kid := c.Children(id)[0]
+				s.nodeFollow[kid].Union(follow)
+				s.nodeFollow[kid].Union(s.nodeFirst[kid])
+			case ebnf.SequenceNode:
+				// acc is what can follow the current kid
+				copy(acc, follow)
+				kids := c.Children(id)
+				for i := len(kids) - 1; i >= 0; i-- {
+					kid := kids[i]
+					s.nodeFollow[kid].Union(acc)
+					if !s.nodeNullable[kid] {
+						copy(acc, s.nodeFirst[kid])
+					} else {
+						acc.Union(s.nodeFirst[kid])
+					}
+				}
+			}
+		}
+	}
+}
+
+// Compiled returns the compiled grammar.
+func (s *Sets) Compiled() *ebnf.Compiled { return s.c }
+
+// Starts returns the start symbols.
+func (s *Sets) Starts() []ebnf.Symbol { return s.starts }
+
+// EOF returns the element of a FOLLOW set that stands for the end of input.
+// It is one more than the largest terminal symbol.
+func (s *Sets) EOF() int { return s.c.NumTerminals() }
+
+// Nullable returns true if the nonterminal can derive the empty string.
+func (s *Sets) Nullable(sym ebnf.Symbol) bool { return s.nullable[sym] }
+
+// First returns the terminals that can start a string derived from the nonterminal.
+func (s *Sets) First(sym ebnf.Symbol) ebnf.Bitset { return s.first[sym] }
+
+// Follow returns the terminals that can follow the nonterminal.
+func (s *Sets) Follow(sym ebnf.Symbol) ebnf.Bitset { return s.follow[sym] }
+
+// NodeNullable returns true if the node can derive the empty string.
+func (s *Sets) NodeNullable(id ebnf.NodeID) bool { return s.nodeNullable[id] }
+
+// NodeFirst returns the terminals that can start a string derived from the node.
+func (s *Sets) NodeFirst(id ebnf.NodeID) ebnf.Bitset { return s.nodeFirst[id] }
+
+// NodeFollow returns the terminals that can follow the node.
+func (s *Sets) NodeFollow(id ebnf.NodeID) ebnf.Bitset { return s.nodeFollow[id] }
+
+// Names returns the names of the terminals in the set.
+// The end of input is named "$".
+func (s *Sets) Names(set ebnf.Bitset) []string {
+	var names []string
+	for _, i := range set.Elements() {
+		if i == s.EOF() {
+			names = append(names, "$")
+		} else {
+			names = append(names, s.c.Terminal(ebnf.Symbol(i)))
+		}
+	}
+	return names
+}
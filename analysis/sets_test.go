@@ -0,0 +1,121 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src string) ebnf.Grammar {
+	t.Helper()
+	grammar, errs := ebnf.Parse([]byte(src))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	return grammar
+}
+
+func names(s *Sets, set ebnf.Bitset) string {
+	return strings.Join(s.Names(set), " ")
+}
+
+func TestSets(t *testing.T) {
+	sets, err := Analyze(parse(t, `
+		list  = item { Comma item } [ Semi ] .
+		item  = [ Minus ] num .
+		num   = Digit { Digit } | empty .
+		empty = .`), "list")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	c := sets.Compiled()
+	for _, tc := range []struct {
+		name     string
+		nullable bool
+		first    string
+		follow   string
+	}{
+		{"list", true, "Comma Semi Minus Digit", "$"},
+		{"item", true, "Minus Digit", "Comma Semi $"},
+		{"num", true, "Digit", "Comma Semi $"},
+		{"empty", true, "", "Comma Semi $"},
+	} {
+		sym, _ := c.NonTerminalID(tc.name)
+		if got := sets.Nullable(sym); got != tc.nullable {
+			t.Errorf("%s: nullable: want %v, got %v", tc.name, tc.nullable, got)
+		}
+		if got := names(sets, sets.First(sym)); got != tc.first {
+			t.Errorf("%s: first: want %q, got %q", tc.name, tc.first, got)
+		}
+		if got := names(sets, sets.Follow(sym)); got != tc.follow {
+			t.Errorf("%s: follow: want %q, got %q", tc.name, tc.follow, got)
+		}
+	}
+
+	// the repetition in num can be followed by another Digit
+	sym, _ := c.NonTerminalID("num")
+	seq := c.Children(c.Children(c.Root(sym))[0])
+	rep := seq[1]
+	if c.Node(rep).Kind != ebnf.RepetitionNode {
+		t.Fatalf("num: want repetition, got %s", c.Node(rep).Kind)
+	}
+	body := c.Children(rep)[0]
+	if got, want := names(sets, sets.NodeFollow(body)), "Comma Semi Digit $"; got != want {
+		t.Errorf("num: repetition body follow: want %q, got %q", want, got)
+	}
+	if !sets.NodeNullable(rep) || sets.NodeNullable(seq[0]) {
+		t.Errorf("num: wrong nullable for repetition or Digit")
+	}
+
+	if _, err := Analyze(parse(t, `a = b .`), "b"); err == nil {
+		t.Errorf("Analyze should fail for an undefined start")
+	}
+}
+
+func TestSetsLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets, err := Analyze(parse(t, string(input)), "chunk")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	c := sets.Compiled()
+	for _, tc := range []struct {
+		name      string
+		nullable  bool
+		first     []string // must be in FIRST
+		follow    []string // must be in FOLLOW
+		notFollow []string // must not be in FOLLOW
+	}{
+		{"block", true, []string{"SemiColon", "Return", "Name", "LParen"}, []string{"$", "End", "Until", "Else", "ElseIf"}, []string{"Do"}},
+		{"attrib", true, []string{"LT"}, []string{"Comma", "EQ", "$"}, nil},
+		{"exp", false, []string{"Nil", "Minus", "LCurly", "LParen"}, []string{"Plus", "Then", "RParen", "Do"}, nil},
+	} {
+		sym, _ := c.NonTerminalID(tc.name)
+		if got := sets.Nullable(sym); got != tc.nullable {
+			t.Errorf("%s: nullable: want %v, got %v", tc.name, tc.nullable, got)
+		}
+		check := func(what string, set ebnf.Bitset, list []string, want bool) {
+			for _, name := range list {
+				i := sets.EOF()
+				if name != "$" {
+					term, _ := c.TerminalID(name)
+					i = int(term)
+				}
+				if set.Has(i) != want {
+					t.Errorf("%s: %s: %s: want %v, got %v", tc.name, what, name, want, !want)
+				}
+			}
+		}
+		check("first", sets.First(sym), tc.first, true)
+		check("follow", sets.Follow(sym), tc.follow, true)
+		check("follow", sets.Follow(sym), tc.notFollow, false)
+	}
+}
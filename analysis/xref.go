@@ -0,0 +1,147 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/tokens"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Use is a reference to a name in a production.
+type Use struct {
+	Production string
+	Pos        tokens.Position
+}
+
+// An XRefEntry is a terminal or nonterminal with the places it is
+// defined and used.
+type XRefEntry struct {
+	Name     string
+	Terminal bool
+	Defined  bool
+	Def      tokens.Position // position of the production, if defined
+	Uses     []Use           // in source order
+}
+
+// An XRef is the cross reference of a grammar.
+type XRef struct {
+	Terminals    []*XRefEntry // sorted by name
+	NonTerminals []*XRefEntry // sorted by name
+}
+
+// A Suspect is a name that is often a typo: a terminal used only once,
+// or names that differ only in case.
+type Suspect struct {
+	Pos     tokens.Position
+	Names   []string
+	Message string
+}
+
+func (s Suspect) Error() string {
+	return fmt.Sprintf("%d: %s: %s", s.Pos.Line, strings.Join(s.Names, ", "), s.Message)
+}
+
+// CrossReference returns the cross reference of the grammar. A name is
+// a terminal if it starts with an upper case letter.
+func CrossReference(grammar ebnf.Grammar) *XRef {
+	entries := make(map[string]*XRefEntry)
+	entry := func(name string) *XRefEntry {
+		e := entries[name]
+		if e == nil {
+			r, _ := utf8.DecodeRuneInString(name)
+			e = &XRefEntry{Name: name, Terminal: unicode.IsUpper(r)}
+			entries[name] = e
+		}
+		return e
+	}
+	for name, prod := range grammar {
+		e := entry(name)
+		e.Defined, e.Def = true, ebnf.Position(prod)
+		ebnf.Apply(prod.Expr, func(c *ebnf.Cursor) bool {
+			switch x := c.Node().(type) {
+			case *ebnf.Name, *ebnf.Literal:
+				e := entry(x.(fmt.Stringer).String())
+				e.Uses = append(e.Uses, Use{Production: name, Pos: ebnf.Position(x)})
+			}
+			return true
+		}, nil)
+	}
+
+	x := &XRef{}
+	for _, e := range entries {
+		uses := e.Uses
+		sort.Slice(uses, func(i, j int) bool { return before(uses[i].Pos, uses[j].Pos) })
+		if e.Terminal {
+			x.Terminals = append(x.Terminals, e)
+		} else {
+			x.NonTerminals = append(x.NonTerminals, e)
+		}
+	}
+	for _, list := range [][]*XRefEntry{x.Terminals, x.NonTerminals} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return x
+}
+
+// Suspects returns the terminals that are used only once and the groups
+// of names that differ only in case, in source order.
+func (x *XRef) Suspects() []Suspect {
+	var list []Suspect
+	for _, e := range x.Terminals {
+		if len(e.Uses) == 1 && !e.Defined {
+			list = append(list, Suspect{Pos: e.Uses[0].Pos, Names: []string{e.Name}, Message: "terminal is used only once"})
+		}
+	}
+	folded := make(map[string][]*XRefEntry)
+	var keys []string
+	for _, list := range [][]*XRefEntry{x.Terminals, x.NonTerminals} {
+		for _, e := range list {
+			key := strings.ToLower(e.Name)
+			if folded[key] == nil {
+				keys = append(keys, key)
+			}
+			folded[key] = append(folded[key], e)
+		}
+	}
+	for _, key := range keys {
+		group := folded[key]
+		if len(group) < 2 {
+			continue
+		}
+		s := Suspect{Message: "names differ only in case"}
+		for _, e := range group {
+			s.Names = append(s.Names, e.Name)
+			if pos := e.first(); s.Pos.Line == 0 || before(pos, s.Pos) {
+				s.Pos = pos
+			}
+		}
+		sort.Strings(s.Names)
+		list = append(list, s)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return before(list[i].Pos, list[j].Pos) })
+	return list
+}
+
+// first returns the position of the definition or first use of the name.
+func (e *XRefEntry) first() tokens.Position {
+	if e.Defined && (len(e.Uses) == 0 || before(e.Def, e.Uses[0].Pos)) {
+		return e.Def
+	} else if len(e.Uses) != 0 {
+		return e.Uses[0].Pos
+	}
+	return e.Def
+}
+
+func before(a, b tokens.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Col < b.Col
+}
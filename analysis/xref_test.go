@@ -0,0 +1,61 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCrossReference(t *testing.T) {
+	x := CrossReference(parse(t, `
+		s = a Semi { a Semi } | Id .
+		a = Id Eq b | ID .
+		b = c bb .
+		bB = c .`))
+	var got []string
+	for _, list := range [][]*XRefEntry{x.Terminals, x.NonTerminals} {
+		for _, e := range list {
+			line := fmt.Sprintf("%s %v", e.Name, e.Terminal)
+			if e.Defined {
+				line += fmt.Sprintf(" def %d:%d", e.Def.Line, e.Def.Col)
+			}
+			for _, u := range e.Uses {
+				line += fmt.Sprintf(" %s@%d:%d", u.Production, u.Pos.Line, u.Pos.Col)
+			}
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"Eq true a@3:10",
+		"ID true a@3:17",
+		"Id true s@2:27 a@3:7",
+		"Semi true s@2:9 s@2:18",
+		"a false def 3:3 s@2:7 s@2:16",
+		"b false def 4:3 a@3:13",
+		"bB false def 5:3",
+		"bb false b@4:9",
+		"c false b@4:7 bB@5:8",
+		"s false def 2:3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	got = nil
+	for _, s := range x.Suspects() {
+		got = append(got, s.Error())
+	}
+	want = []string{
+		"2: ID, Id: names differ only in case",
+		"3: Eq: terminal is used only once",
+		"3: ID: terminal is used only once",
+		"4: bB, bb: names differ only in case",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import "math/bits"
+
+// A Bitset is a fixed size set of small non-negative integers,
+// such as the symbols of a Compiled grammar.
+// The methods that change the set do so in place.
+type Bitset []uint64
+
+// NewBitset returns an empty set that can hold the integers 0 through n-1.
+func NewBitset(n int) Bitset {
+	return make(Bitset, (n+63)/64)
+}
+
+// Has returns true if i is in the set.
+func (b Bitset) Has(i int) bool {
+	return b[i/64]&(1<<(i%64)) != 0
+}
+
+// Set adds i to the set.
+func (b Bitset) Set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+// Clear removes i from the set.
+func (b Bitset) Clear(i int) {
+	b[i/64] &^= 1 << (i % 64)
+}
+
+// Union adds the elements of o to the set.
+// It returns true if the set changed.
+func (b Bitset) Union(o Bitset) (changed bool) {
+	for i, w := range o {
+		if b[i]|w != b[i] {
+			b[i], changed = b[i]|w, true
+		}
+	}
+	return changed
+}
+
+// Intersects returns true if the sets have an element in common.
+func (b Bitset) Intersects(o Bitset) bool {
+	for i, w := range o {
+		if b[i]&w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersection returns a new set with the elements in both sets.
+func (b Bitset) Intersection(o Bitset) Bitset {
+	c := b.Clone()
+	for i := range c {
+		c[i] &= o[i]
+	}
+	return c
+}
+
+// IsEmpty returns true if the set has no elements.
+func (b Bitset) IsEmpty() bool {
+	for _, w := range b {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of elements in the set.
+func (b Bitset) Len() (n int) {
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Elements returns the elements of the set in increasing order.
+func (b Bitset) Elements() (list []int) {
+	for i, w := range b {
+		for w != 0 {
+			list = append(list, i*64+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return list
+}
+
+// Equal returns true if the sets have the same elements.
+func (b Bitset) Equal(o Bitset) bool {
+	if len(b) != len(o) {
+		return false
+	}
+	for i, w := range b {
+		if o[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a copy of the set.
+func (b Bitset) Clone() Bitset {
+	return append(Bitset(nil), b...)
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf/tokens"
+	"unicode"
+)
+
+// A Builder constructs a Grammar without going through the scanner and parser.
+//
+//	b := ebnf.NewBuilder()
+//	b.Prod("stat").Alt(
+//		b.T("SemiColon"),
+//		b.Seq(b.T("While"), b.N("exp"), b.T("Do"), b.N("block"), b.T("End")),
+//	)
+//	grammar, errs := b.Grammar()
+//
+// The nodes it creates carry synthetic positions.
+// Every call to Prod starts a new line and every token created
+// after that advances the column on that line.
+type Builder struct {
+	line, col int
+	prods     []*Production
+	errors    errorList
+}
+
+// NewBuilder returns a Builder with no productions.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// A ProductionBuilder sets the expression of a production started by Builder.Prod.
+type ProductionBuilder struct {
+	b    *Builder
+	prod *Production
+}
+
+// Prod starts a new production with the given name.
+// A name that starts with an upper case letter starts a lexical production.
+// The production is empty until one of the ProductionBuilder methods is called.
+func (b *Builder) Prod(name string) *ProductionBuilder {
+	b.line, b.col = b.line+1, 0
+	var prod *Production
+	if isName(name, unicode.IsUpper) {
+		prod = &Production{Name: &Name{tok: b.token(tokens.TERMINAL, name)}}
+	} else {
+		prod = &Production{Name: b.N(name)}
+	}
+	b.prods = append(b.prods, prod)
+	return &ProductionBuilder{b: b, prod: prod}
+}
+
+// Expr sets the expression of the production.
+// A nil expression leaves the production empty.
+func (pb *ProductionBuilder) Expr(x Expression) *Production {
+	pb.prod.Expr = x
+	return pb.prod
+}
+
+// Alt sets the expression of the production to the list of alternatives.
+func (pb *ProductionBuilder) Alt(list ...Expression) *Production {
+	return pb.Expr(pb.b.Alt(list...))
+}
+
+// Seq sets the expression of the production to the sequence.
+func (pb *ProductionBuilder) Seq(list ...Expression) *Production {
+	return pb.Expr(pb.b.Seq(list...))
+}
+
+// N returns a reference to the nonterminal with the given name.
+func (b *Builder) N(name string) *Name {
+	tok := b.token(tokens.NONTERMINAL, name)
+	if !isName(name, unicode.IsLower) {
+		b.error(tok, "invalid nonterminal name %q", name)
+	}
+	return &Name{tok: tok}
+}
+
+// T returns a reference to the terminal with the given name.
+func (b *Builder) T(name string) *Literal {
+	tok := b.token(tokens.TERMINAL, name)
+	if !isName(name, unicode.IsUpper) {
+		b.error(tok, "invalid terminal name %q", name)
+	}
+	return &Literal{tok: tok}
+}
+
+// Alt returns an expression matching any one of the alternatives.
+// Like the parser, it returns the expression itself when there is only one.
+func (b *Builder) Alt(list ...Expression) Expression {
+	if len(list) == 0 {
+		return b.bad("empty alternative")
+	} else if len(list) == 1 {
+		return b.check(list[0])
+	}
+	x := make(Alternative, 0, len(list))
+	for _, e := range list {
+		if alt, ok := e.(Alternative); ok {
+			x = append(x, b.Group(alt))
+		} else {
+			x = append(x, b.check(e))
+		}
+	}
+	return x
+}
+
+// Seq returns an expression matching each of the terms in order.
+// Like the parser, it returns the term itself when there is only one.
+func (b *Builder) Seq(list ...Expression) Expression {
+	if len(list) == 0 {
+		return b.bad("empty sequence")
+	} else if len(list) == 1 {
+		return b.check(list[0])
+	}
+	x := make(Sequence, 0, len(list))
+	for _, e := range list {
+		switch e.(type) {
+		case Alternative, Sequence:
+			x = append(x, b.Group(e))
+		default:
+			x = append(x, b.check(e))
+		}
+	}
+	return x
+}
+
+// Group returns the grouped expression (body).
+func (b *Builder) Group(body Expression) *Group {
+	return &Group{tok: b.token(tokens.START_GROUP, ""), Body: b.check(body)}
+}
+
+// Opt returns the optional expression [body].
+func (b *Builder) Opt(body Expression) *Option {
+	return &Option{tok: b.token(tokens.START_OPTION, ""), Body: b.check(body)}
+}
+
+// Rep returns the repeated expression {body}.
+func (b *Builder) Rep(body Expression) *Repetition {
+	return &Repetition{tok: b.token(tokens.START_REPETITION, ""), Body: b.check(body)}
+}
+
+// Grammar returns the productions built so far.
+// Like Parse, it reports an error for every production that is defined more than once
+// along with any errors found while building the expressions.
+func (b *Builder) Grammar() (Grammar, []error) {
+	errors := append(errorList(nil), b.errors...)
+	grammar := make(Grammar)
+	for _, prod := range b.prods {
+		if err := grammar.define(prod); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if len(errors) == 0 {
+		return grammar, nil
+	}
+	return grammar, errors
+}
+
+// check reports an error if the expression is missing.
+func (b *Builder) check(x Expression) Expression {
+	if x == nil {
+		return b.bad("missing expression")
+	}
+	return x
+}
+
+// bad returns a Bad node and records the error.
+func (b *Builder) bad(msg string) *Bad {
+	tok := b.token(tokens.UNKNOWN, "")
+	return &Bad{tok: tok, err: b.error(tok, msg)}
+}
+
+func (b *Builder) error(tok *tokens.Token, format string, args ...any) error {
+	err := fmt.Errorf("%d: %s", tok.Line(), fmt.Sprintf(format, args...))
+	b.errors = append(b.errors, err)
+	return err
+}
+
+// token returns a new token at the next synthetic position.
+func (b *Builder) token(kind tokens.Kind, text string) *tokens.Token {
+	b.col++
+	tok := &tokens.Token{Pos: tokens.Position{Line: b.line, Col: b.col}, Kind: kind}
+	if text != "" {
+		tok.Text = []byte(text)
+	}
+	return tok
+}
+
+// isName returns true if the name would be scanned as a single
+// NONTERMINAL or TERMINAL token, depending on the case of the first letter.
+func isName(name string, first func(rune) bool) bool {
+	for i, r := range name {
+		if i == 0 && !first(r) {
+			return false
+		} else if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return false
+		}
+	}
+	return name != ""
+}
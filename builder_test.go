@@ -0,0 +1,66 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder()
+	b.Prod("program").Seq(b.N("song"))
+	b.Prod("song").Expr(b.Rep(b.N("note")))
+	b.Prod("note").Alt(b.T("Do"), b.Group(b.Alt(b.Seq(b.T("Re"), b.T("Mi")), b.T("Fa"), b.Seq(b.T("So"), b.T("La")))), b.N("ti"))
+	b.Prod("ti").Expr(b.T("Ti"))
+	b.Prod("rest").Expr(nil)
+	b.Prod("start").Alt(b.N("program"), b.N("rest"))
+	grammar, errs := b.Grammar()
+	if errs != nil {
+		t.Fatalf("Grammar() failed: %v", errs)
+	}
+	if errs = Verify(grammar, "start"); errs != nil {
+		t.Errorf("Verify() failed: %v", errs)
+	}
+	if got := grammar["note"].Pos(); got != 3 {
+		t.Errorf("note: want line 3, got %d", got)
+	}
+	if _, ok := grammar["note"].Expr.(Alternative); !ok {
+		t.Errorf("note: want Alternative, got %T", grammar["note"].Expr)
+	}
+	if _, ok := grammar["program"].Expr.(*Name); !ok {
+		t.Errorf("program: want *Name, got %T", grammar["program"].Expr)
+	}
+
+	// an upper case name starts a lexical production
+	b = NewBuilder()
+	b.Prod("program").Seq(b.T("Digits"), b.T("Dot"))
+	b.Prod("Digits").Seq(b.T("Digit"), b.Rep(b.T("Digit")))
+	grammar, errs = b.Grammar()
+	if errs != nil {
+		t.Fatalf("Grammar() failed: %v", errs)
+	}
+	if errs = Verify(grammar, "program"); errs != nil {
+		t.Errorf("Verify() failed: %v", errs)
+	}
+	if !isLexical(grammar["Digits"]) || isLexical(grammar["program"]) {
+		t.Errorf("want only Digits to be lexical")
+	}
+
+	for _, tc := range []struct {
+		id    int
+		build func(b *Builder)
+	}{
+		{1, func(b *Builder) { b.Prod("a").Seq(b.T("A")); b.Prod("a").Seq(b.T("B")) }},
+		{2, func(b *Builder) { b.Prod("1a").Seq(b.T("A")) }},
+		{3, func(b *Builder) { b.Prod("a").Seq(b.T("b")) }},
+		{4, func(b *Builder) { b.Prod("a").Alt() }},
+		{5, func(b *Builder) { b.Prod("a").Seq(b.T("A"), nil) }},
+		{6, func(b *Builder) { b.Prod("a").Seq(b.N("a-b")) }},
+	} {
+		b := NewBuilder()
+		tc.build(b)
+		if _, errs := b.Grammar(); errs == nil {
+			t.Errorf("%d: Grammar() should have failed", tc.id)
+		}
+	}
+}
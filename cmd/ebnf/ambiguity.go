@@ -0,0 +1,62 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"strings"
+)
+
+func init() {
+	var opts analysis.AmbiguityOptions
+	commands = append(commands, &command{
+		name:  "ambiguity",
+		args:  "[-start name] [-length n] [-limit n] file",
+		short: "search for a sentence with two parse trees",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&opts.Start, "start", "", "production to check (default is every production)")
+			fs.IntVar(&opts.MaxLength, "length", 6, "longest sentence to try")
+			fs.IntVar(&opts.MaxSentences, "limit", 500000, "most sentences to keep, 0 for no limit")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			c, err := ebnf.Compile(grammar)
+			if err != nil {
+				return err
+			}
+			a, err := analysis.FindAmbiguity(analysis.NewBNF(c), opts)
+			if err != nil {
+				return err
+			} else if a == nil {
+				fmt.Printf("no ambiguous sentences up to length %d\n", opts.MaxLength)
+				return nil
+			}
+			fmt.Printf("%s:%v\n", args[0], a.Error())
+			for i, t := range a.Trees {
+				fmt.Printf("\ntree %d:\n", i+1)
+				printTree(t, 1)
+			}
+			return errSilent
+		},
+	})
+}
+
+// printTree prints the tree with one node per line.
+func printTree(t *analysis.Tree, depth int) {
+	fmt.Printf("%s%s\n", strings.Repeat("    ", depth), t.Symbol)
+	for _, kid := range t.Kids {
+		printTree(kid, depth+1)
+	}
+}
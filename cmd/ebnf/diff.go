@@ -0,0 +1,64 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:  "diff",
+		args:  "old new",
+		short: "report changes to the language between two grammars",
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 2 {
+				fs.Usage()
+				return errSilent
+			}
+			old, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			new, err := load(args[1])
+			if err != nil {
+				return err
+			}
+			changes := ebnf.Diff(old, new)
+			for _, c := range changes {
+				switch c.Kind {
+				case ebnf.Added:
+					fmt.Printf("+ %s (%s:%d)\n", c.Name, args[1], c.New.Pos())
+				case ebnf.Removed:
+					fmt.Printf("- %s (%s:%d)\n", c.Name, args[0], c.Old.Pos())
+				case ebnf.Renamed:
+					fmt.Printf("~ %s -> %s (%s:%d, %s:%d)\n", c.Name, c.NewName, args[0], c.Old.Pos(), args[1], c.New.Pos())
+				case ebnf.Changed:
+					fmt.Printf("~ %s (%s:%d, %s:%d)\n", c.Name, args[0], c.Old.Pos(), args[1], c.New.Pos())
+					for _, x := range c.Removed {
+						fmt.Printf("    - %s\n", alternative(x))
+					}
+					for _, x := range c.Added {
+						fmt.Printf("    + %s\n", alternative(x))
+					}
+				}
+			}
+			if len(changes) != 0 {
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
+
+// alternative returns the text of an alternative for a report.
+func alternative(x ebnf.Expression) string {
+	if x == nil {
+		return "(empty)"
+	}
+	return ebnf.ExprString(x)
+}
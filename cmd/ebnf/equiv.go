@@ -0,0 +1,63 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+)
+
+func init() {
+	var opts analysis.EquivalenceOptions
+	commands = append(commands, &command{
+		name:  "equiv",
+		args:  "[-start name] [-start2 name] [-length n] [-limit n] old new",
+		short: "search for a sentence derived by only one of two grammars",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&opts.Starts[0], "start", "", "start production of old (default is the first declared with \"; ebnf:start\", or the first production)")
+			fs.StringVar(&opts.Starts[1], "start2", "", "start production of new (default is the start production of old)")
+			fs.IntVar(&opts.MaxLength, "length", 8, "longest sentence to try")
+			fs.IntVar(&opts.MaxStates, "limit", 1000000, "most parser states to keep, 0 for no limit")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 2 {
+				fs.Usage()
+				return errSilent
+			}
+			var b [2]*analysis.BNF
+			for i, src := range args {
+				grammar, err := load(src)
+				if err != nil {
+					return err
+				}
+				c, err := ebnf.Compile(grammar)
+				if err != nil {
+					return err
+				}
+				b[i] = analysis.NewBNF(c)
+				if opts.Starts[i] != "" {
+					continue
+				} else if i == 1 {
+					opts.Starts[1] = opts.Starts[0]
+				} else if starts := ebnf.StartSymbols(grammar); len(starts) != 0 {
+					opts.Starts[0] = starts[0]
+				} else {
+					opts.Starts[0] = firstProduction(grammar)
+				}
+			}
+			d, err := analysis.FindDifference(b[0], b[1], opts)
+			if err != nil {
+				return err
+			} else if d == nil {
+				fmt.Printf("no differences in sentences up to length %d\n", opts.MaxLength)
+				return nil
+			}
+			fmt.Println(d.Error())
+			return errSilent
+		},
+	})
+}
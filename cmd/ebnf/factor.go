@@ -0,0 +1,36 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/transform"
+	"os"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:  "factor",
+		args:  "file",
+		short: "print the grammar with common prefixes factored out",
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			result, unfactored := transform.LeftFactor(grammar)
+			for _, u := range unfactored {
+				fmt.Fprintf(os.Stderr, "%s:%v\n", args[0], u)
+			}
+			return ebnf.Fprint(os.Stdout, result)
+		},
+	})
+}
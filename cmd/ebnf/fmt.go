@@ -0,0 +1,84 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"io"
+	"os"
+)
+
+func init() {
+	var write, diff bool
+	commands = append(commands, &command{
+		name:  "fmt",
+		args:  "[-w] [-d] [files]",
+		short: "rewrite grammars in canonical form",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&write, "w", false, "write result to (source) file instead of stdout")
+			fs.BoolVar(&diff, "d", false, "display diffs instead of rewriting files")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				if write {
+					return fmt.Errorf("cannot use -w with standard input")
+				}
+				input, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return err
+				}
+				return format("<standard input>", input, false, diff)
+			}
+			var failed bool
+			for _, src := range args {
+				input, err := os.ReadFile(src)
+				if err == nil {
+					err = format(src, input, write, diff)
+				}
+				if err != nil {
+					if err != errSilent {
+						fmt.Fprintf(os.Stderr, "%s: %v\n", src, err)
+					}
+					failed = true
+				}
+			}
+			if failed {
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
+
+// format formats the input and writes the result to stdout,
+// back to the source file, or as a diff against the input.
+func format(src string, input []byte, write, diff bool) error {
+	grammar, errors := ebnf.Parse(input)
+	if errors != nil {
+		for _, err := range errors {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", src, err)
+		}
+		return errSilent
+	}
+	var output bytes.Buffer
+	if err := ebnf.Fprint(&output, grammar); err != nil {
+		return err
+	}
+	if diff {
+		os.Stdout.Write(unifiedDiff(src+".orig", input, src, output.Bytes()))
+	}
+	if write {
+		if !bytes.Equal(input, output.Bytes()) {
+			return os.WriteFile(src, output.Bytes(), 0644)
+		}
+	} else if !diff {
+		_, err := os.Stdout.Write(output.Bytes())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"github.com/mdhender/ebnf/transform"
+	"os"
+)
+
+func init() {
+	var start string
+	var fix bool
+	commands = append(commands, &command{
+		name:  "leftrec",
+		args:  "[-start name] [-fix] file",
+		short: "report or remove left recursive productions",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&start, "start", "", "start production (default is the first production)")
+			fs.BoolVar(&fix, "fix", false, "print the grammar with the left recursion removed")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			if fix {
+				result, origins, errors := transform.EliminateLeftRecursion(grammar)
+				if errors != nil {
+					for _, err := range errors {
+						fmt.Fprintf(os.Stderr, "%s:%v\n", args[0], err)
+					}
+					return errSilent
+				}
+				for _, o := range origins {
+					fmt.Fprintf(os.Stderr, "%s:%v\n", args[0], o)
+				}
+				return ebnf.Fprint(os.Stdout, result)
+			}
+			if start == "" {
+				start = firstProduction(grammar)
+			}
+			sets, err := analysis.Analyze(grammar, start)
+			if err != nil {
+				return err
+			}
+			cycles := analysis.LeftRecursion(sets)
+			for _, c := range cycles {
+				fmt.Printf("%s:%v\n", args[0], c.Error())
+			}
+			if len(cycles) != 0 {
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf/lint"
+	"sort"
+	"strings"
+)
+
+func init() {
+	var list bool
+	var enable, disable, options, severities string
+	commands = append(commands, &command{
+		name:  "lint",
+		args:  "[-list] [-enable rules] [-disable rules] [-set rule.option=value,...] [-severity rule=level,...] file",
+		short: "check a grammar against style and redundancy rules",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&list, "list", false, "list the registered rules")
+			fs.StringVar(&enable, "enable", "", "comma separated rules to run besides the default ones")
+			fs.StringVar(&disable, "disable", "", "comma separated rules not to run")
+			fs.StringVar(&options, "set", "", "comma separated rule options, as in max-alternatives.max=10")
+			fs.StringVar(&severities, "severity", "", "comma separated rule severities, as in redundant=error")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if list {
+				for _, rule := range lint.Rules() {
+					var opts []string
+					for k, v := range rule.Options {
+						opts = append(opts, k+"="+v)
+					}
+					sort.Strings(opts)
+					def := ""
+					if rule.Default {
+						def = " (default)"
+					}
+					fmt.Printf("%-20s %-8s %s%s\n", rule.Name, rule.Severity, rule.Doc, def)
+					for _, opt := range opts {
+						fmt.Printf("    %s\n", opt)
+					}
+				}
+				return nil
+			}
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			l, err := linter(enable, disable, options, severities)
+			if err != nil {
+				return err
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			problems, err := l.Run(grammar)
+			if err != nil {
+				return err
+			}
+			failed := false
+			for _, p := range problems {
+				fmt.Printf("%s:%v\n", args[0], p.Error())
+				if p.Severity >= lint.Warning {
+					failed = true
+				}
+			}
+			if failed {
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
+
+// linter returns the default linter configured by the flags.
+func linter(enable, disable, options, severities string) (*lint.Linter, error) {
+	l := lint.Default()
+	opts := make(map[string]map[string]string) // by rule
+	for _, item := range split(options) {
+		key, value, ok := strings.Cut(item, "=")
+		rule, option, ok2 := strings.Cut(key, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid option %q", item)
+		}
+		if opts[rule] == nil {
+			opts[rule] = make(map[string]string)
+		}
+		opts[rule][option] = value
+	}
+	// enable the default rules, then the requested ones, in that order
+	var names []string
+	enabled := make(map[string]bool)
+	for _, name := range append(l.Enabled(), split(enable)...) {
+		if !enabled[name] {
+			enabled[name], names = true, append(names, name)
+		}
+	}
+	for rule := range opts {
+		if !enabled[rule] {
+			return nil, fmt.Errorf("options for rule %q, which is not enabled", rule)
+		}
+	}
+	for _, name := range names {
+		if err := l.Enable(name, opts[name]); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range split(disable) {
+		l.Disable(name)
+	}
+	for _, item := range split(severities) {
+		rule, level, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid severity %q", item)
+		}
+		severity, err := lint.ParseSeverity(level)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.SetSeverity(rule, severity); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// split returns the comma separated items of the list.
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf/analysis"
+)
+
+func init() {
+	var start string
+	commands = append(commands, &command{
+		name:  "ll1",
+		args:  "[-start name] file",
+		short: "report LL(1) conflicts",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&start, "start", "", "start production (default is the first production)")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			if start == "" {
+				start = firstProduction(grammar)
+			}
+			sets, err := analysis.Analyze(grammar, start)
+			if err != nil {
+				return err
+			}
+			conflicts := analysis.LL1(sets)
+			for _, c := range conflicts {
+				fmt.Printf("%s:%v\n", args[0], c.Error())
+			}
+			if len(conflicts) != 0 {
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
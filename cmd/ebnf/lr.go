@@ -0,0 +1,84 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"github.com/mdhender/ebnf/lr"
+)
+
+// methods are the names of the LR methods for the -method flag.
+var methods = map[string]lr.Method{
+	"lr0":     lr.LR0,
+	"slr":     lr.SLR,
+	"lalr":    lr.LALR,
+	"lr1":     lr.LR1,
+	"minimal": lr.MinimalLR1,
+}
+
+func init() {
+	var start, method string
+	var states, compare bool
+	commands = append(commands, &command{
+		name:  "lr",
+		args:  "[-start name] [-method lr0|slr|lalr|lr1|minimal] [-states] [-compare] file",
+		short: "build an LR automaton and report its conflicts",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&start, "start", "", "start production (default is the first production)")
+			fs.StringVar(&method, "method", "lalr", "method used to build the automaton")
+			fs.BoolVar(&states, "states", false, "print every state")
+			fs.BoolVar(&compare, "compare", false, "compare the state counts and conflicts of every method")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			m, ok := methods[method]
+			if len(args) != 1 || !ok {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			if start == "" {
+				start = firstProduction(grammar)
+			}
+			c, err := ebnf.Compile(grammar)
+			if err != nil {
+				return err
+			}
+			if compare {
+				list, err := lr.Compare(analysis.NewBNF(c), start)
+				if err != nil {
+					return err
+				}
+				for _, s := range list {
+					fmt.Println(s)
+				}
+				return nil
+			}
+			a, err := lr.Build(analysis.NewBNF(c), start, m)
+			if err != nil {
+				return err
+			}
+			if states {
+				for i := range a.States {
+					fmt.Print(a.StateString(i))
+				}
+			}
+			for _, c := range a.Conflicts {
+				fmt.Printf("%s:%v\n", args[0], c.Error())
+			}
+			sr, rr := a.Count()
+			fmt.Println(lr.Summary{Method: a.Method, States: len(a.States), ShiftReduce: sr, ReduceReduce: rr})
+			if len(a.Conflicts) != 0 {
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
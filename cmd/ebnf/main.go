@@ -2,26 +2,77 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the COPYING file.
 
+// Command ebnf runs tools on EBNF grammar files.
+//
+// Usage:
+//
+//	ebnf <command> [flags] [files]
+//
+// Run "ebnf help" for the list of commands.
 package main
 
 import (
+	"flag"
 	"fmt"
-	"github.com/mdhender/ebnf"
 	"log"
 	"os"
 )
 
+// A command is one of the sub-commands of ebnf.
+type command struct {
+	name  string
+	args  string // usage for the arguments
+	short string // one line description
+	run   func(fs *flag.FlagSet, args []string) error
+	flags func(fs *flag.FlagSet) // defines the flags, may be nil
+}
+
+var commands []*command
+
 func main() {
-	src := "lua.ebnf"
-	if input, err := os.ReadFile(src); err != nil {
-		log.Fatal(err)
-	} else if grammar, errors := ebnf.Parse(input); errors != nil {
-		for _, err := range errors {
-			fmt.Printf("Parse(%s) failed: %v\n", src, err)
+	log.SetFlags(0)
+	log.SetPrefix("ebnf: ")
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: ebnf %s %s\n", cmd.name, cmd.args)
+			fs.PrintDefaults()
 		}
-	} else if errors = ebnf.Verify(grammar, "chunk"); errors != nil {
-		for _, err := range errors {
-			fmt.Printf("Verify(%s) failed: %v\n", src, err)
+		if cmd.flags != nil {
+			cmd.flags(fs)
 		}
+		_ = fs.Parse(args)
+		if err := cmd.run(fs, fs.Args()); err != nil {
+			if err != errSilent {
+				log.Print(err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+	log.Printf("unknown command %q", name)
+	usage()
+	os.Exit(2)
+}
+
+// errSilent is returned by commands that have already reported their failure.
+var errSilent = fmt.Errorf("failed")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: ebnf <command> [flags] [files]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.short)
 	}
 }
@@ -0,0 +1,57 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf/analysis"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func init() {
+	var asJSON bool
+	commands = append(commands, &command{
+		name:  "metrics",
+		args:  "[-json] file",
+		short: "report the size and complexity of a grammar",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&asJSON, "json", false, "write the metrics as JSON")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			m := analysis.Measure(grammar)
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(m)
+			}
+			fmt.Printf("productions       %d\n", m.Productions)
+			fmt.Printf("terminals         %d\n", m.Terminals)
+			fmt.Printf("nonterminals      %d\n", m.NonTerminals)
+			fmt.Printf("alternatives      %d (at most %d in a production)\n", m.Alternatives, m.MaxAlternatives)
+			fmt.Printf("nesting depth     %d\n", m.MaxDepth)
+			fmt.Printf("recursive         %d direct, %d indirect\n", m.Direct, m.Indirect)
+			fmt.Printf("longest chain     %d: %s\n", len(m.LongestChain), strings.Join(m.LongestChain, " -> "))
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintln(w, "line\tproduction\talternatives\tdepth\tfan-in\tfan-out\trecursion\t")
+			for _, pm := range m.PerProduction {
+				fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%s\t\n", pm.Line, pm.Name, pm.Alternatives, pm.Depth, pm.FanIn, pm.FanOut, pm.Recursion)
+			}
+			return w.Flush()
+		},
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"strings"
+)
+
+func init() {
+	var start, prod string
+	commands = append(commands, &command{
+		name:  "sets",
+		args:  "[-start name] [-prod name] file",
+		short: "print nullable, FIRST, and FOLLOW sets",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&start, "start", "", "start production (default is the first production)")
+			fs.StringVar(&prod, "prod", "", "production to print (default is every production)")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			if start == "" {
+				start = firstProduction(grammar)
+			}
+			sets, err := analysis.Analyze(grammar, start)
+			if err != nil {
+				return err
+			}
+			c := sets.Compiled()
+			if prod != "" {
+				sym, ok := c.NonTerminalID(prod)
+				if !ok || !c.Defined(sym) {
+					return fmt.Errorf("no production %q", prod)
+				}
+				printSets(sets, sym, true)
+				return nil
+			}
+			for i := 0; i < c.NumNonTerminals(); i++ {
+				if sym := ebnf.Symbol(i); c.Defined(sym) {
+					printSets(sets, sym, false)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// printSets prints the sets of the production and, if nodes is set,
+// the sets of every subexpression in it.
+func printSets(sets *analysis.Sets, sym ebnf.Symbol, nodes bool) {
+	c := sets.Compiled()
+	fmt.Printf("%s\n", c.NonTerminal(sym))
+	fmt.Printf("    nullable %v\n", sets.Nullable(sym))
+	fmt.Printf("    first    %s\n", setString(sets, sets.First(sym)))
+	fmt.Printf("    follow   %s\n", setString(sets, sets.Follow(sym)))
+	if !nodes || c.Root(sym) == ebnf.NoNode {
+		return
+	}
+	var walk func(id ebnf.NodeID, depth int)
+	walk = func(id ebnf.NodeID, depth int) {
+		n := c.Node(id)
+		indent := strings.Repeat("  ", depth)
+		fmt.Printf("%d:%d:%s %s %s\n", n.Pos.Line, n.Pos.Col, indent, n.Kind, ebnf.ExprString(c.Expr(id)))
+		fmt.Printf("    %s nullable %v\n", indent, sets.NodeNullable(id))
+		fmt.Printf("    %s first    %s\n", indent, setString(sets, sets.NodeFirst(id)))
+		fmt.Printf("    %s follow   %s\n", indent, setString(sets, sets.NodeFollow(id)))
+		for _, kid := range c.Children(id) {
+			walk(kid, depth+1)
+		}
+	}
+	walk(c.Root(sym), 0)
+}
+
+// setString returns the set as a list of terminal names.
+func setString(sets *analysis.Sets, set ebnf.Bitset) string {
+	return "{" + strings.Join(sets.Names(set), " ") + "}"
+}
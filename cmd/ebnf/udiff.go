@@ -0,0 +1,135 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// unifiedDiff returns the differences between the two inputs in
+// unified diff format with three lines of context.
+// It returns nil if the inputs are equal.
+func unifiedDiff(oldName string, old []byte, newName string, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	a, b := splitLines(old), splitLines(new)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// edits is the script that turns a into b.
+	// each edit is ' ', '-', or '+' followed by the line.
+	type edit struct {
+		op   byte
+		line string
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			edits = append(edits, edit{' ', a[i]})
+			i, j = i+1, j+1
+		} else if j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]) {
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		} else {
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+
+	const context = 3
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		// find the next change
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		// extend the hunk until there are more than 2*context unchanged lines
+		end, same := start, 0
+		for k := start; k < len(edits) && same <= 2*context; k++ {
+			if edits[k].op == ' ' {
+				same++
+			} else {
+				end, same = k+1, 0
+			}
+		}
+		lo, hi := start-context, end+context
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(edits) {
+			hi = len(edits)
+		}
+		// line numbers of the hunk in a and b
+		aLine, bLine := 1, 1
+		for _, e := range edits[:lo] {
+			if e.op != '+' {
+				aLine++
+			}
+			if e.op != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, e := range edits[lo:hi] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, e := range edits[lo:hi] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			out.WriteByte('\n')
+		}
+		start = hi
+	}
+	return out.Bytes()
+}
+
+// splitLines splits the input into lines without the line endings.
+func splitLines(input []byte) []string {
+	var lines []string
+	for len(input) != 0 {
+		eol := bytes.IndexByte(input, '\n')
+		if eol == -1 {
+			lines = append(lines, string(input))
+			break
+		}
+		lines = append(lines, string(input[:eol]))
+		input = input[eol+1:]
+	}
+	return lines
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+)
+
+func init() {
+	var start string
+	var reach bool
+	commands = append(commands, &command{
+		name:  "verify",
+		args:  "[-start names] [-reach] file",
+		short: "parse and verify a grammar",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&start, "start", "", "comma separated start productions (default is the ones declared with \"; ebnf:start\", or the first production)")
+			fs.BoolVar(&reach, "reach", false, "report the productions reachable from each start production")
+		},
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			roots := split(start)
+			if len(roots) == 0 {
+				roots = ebnf.StartSymbols(grammar)
+			}
+			if len(roots) == 0 {
+				roots = []string{firstProduction(grammar)}
+			}
+			if reach {
+				reachability(grammar, roots)
+			}
+			if errors := ebnf.Verify(grammar, roots...); errors != nil {
+				for _, err := range errors {
+					fmt.Printf("Verify(%s) failed: %v\n", args[0], err)
+				}
+				return errSilent
+			}
+			return nil
+		},
+	})
+}
+
+// reachability prints the number of productions reachable from each root
+// and the productions that no other root reaches.
+func reachability(grammar ebnf.Grammar, roots []string) {
+	reach := ebnf.Reachability(grammar, roots...)
+	count := make(map[string]int) // number of roots reaching each production
+	for _, list := range reach {
+		for _, name := range list {
+			count[name]++
+		}
+	}
+	for _, root := range roots {
+		var only []string
+		for _, name := range reach[root] {
+			if count[name] == 1 && len(roots) > 1 {
+				only = append(only, name)
+			}
+		}
+		fmt.Printf("%s: reaches %d of %d productions", root, len(reach[root]), len(grammar))
+		if len(only) != 0 {
+			fmt.Printf("; only from %s: %s", root, strings.Join(only, " "))
+		}
+		fmt.Println()
+	}
+}
+
+// load reads and parses a grammar file.
+// Parse errors are printed and reported as a single error.
+func load(src string) (ebnf.Grammar, error) {
+	input, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	grammar, errors := ebnf.Parse(input)
+	if errors != nil {
+		for _, err := range errors {
+			fmt.Printf("Parse(%s) failed: %v\n", src, err)
+		}
+		return nil, errSilent
+	}
+	return grammar, nil
+}
+
+// firstProduction returns the name of the production that comes first in the input.
+func firstProduction(grammar ebnf.Grammar) string {
+	if prods := ebnf.Productions(grammar); len(prods) != 0 {
+		return prods[0].Name.String()
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mdhender/ebnf/analysis"
+	"strings"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:  "xref",
+		args:  "file",
+		short: "list where every terminal and nonterminal is defined and used",
+		run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) != 1 {
+				fs.Usage()
+				return errSilent
+			}
+			grammar, err := load(args[0])
+			if err != nil {
+				return err
+			}
+			x := analysis.CrossReference(grammar)
+			for _, section := range []struct {
+				title   string
+				entries []*analysis.XRefEntry
+			}{
+				{"terminals", x.Terminals},
+				{"nonterminals", x.NonTerminals},
+			} {
+				fmt.Printf("%s\n", section.title)
+				for _, e := range section.entries {
+					var uses []string
+					for _, u := range e.Uses {
+						uses = append(uses, fmt.Sprintf("%d:%d", u.Pos.Line, u.Pos.Col))
+					}
+					def := "-"
+					if e.Defined {
+						def = fmt.Sprintf("%d:%d", e.Def.Line, e.Def.Col)
+					}
+					fmt.Printf("    %-20s %-7s %s\n", e.Name, def, strings.Join(uses, " "))
+				}
+			}
+			for _, s := range x.Suspects() {
+				fmt.Printf("%s:%v\n", args[0], s.Error())
+			}
+			return nil
+		},
+	})
+}
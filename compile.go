@@ -0,0 +1,238 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf/tokens"
+)
+
+// A Symbol is the dense integer ID of a terminal or a nonterminal
+// in a Compiled grammar. Terminals and nonterminals are numbered
+// separately, each starting at zero.
+type Symbol int32
+
+// A NodeID is the index of a node in a Compiled grammar.
+type NodeID int32
+
+// NoNode is the root of a production with an empty expression.
+const NoNode NodeID = -1
+
+// A NodeKind is the kind of a compiled node.
+type NodeKind uint8
+
+const (
+	TerminalNode    NodeKind = iota // a reference to a terminal
+	NonTerminalNode                 // a reference to a nonterminal
+	AlternativeNode                 // children are alternatives
+	SequenceNode                    // children are in sequence
+	OptionNode                      // single child, zero or one times
+	RepetitionNode                  // single child, zero or more times
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case TerminalNode:
+		return "terminal"
+	case NonTerminalNode:
+		return "nonterminal"
+	case AlternativeNode:
+		return "alternative"
+	case SequenceNode:
+		return "sequence"
+	case OptionNode:
+		return "option"
+	case RepetitionNode:
+		return "repetition"
+	}
+	panic(fmt.Sprintf("assert(kind != %d)", k))
+}
+
+// A Node is an expression in a Compiled grammar.
+type Node struct {
+	Kind   NodeKind
+	Symbol Symbol // the terminal or nonterminal referenced by the node
+	Pos    tokens.Position
+	first  int32 // index of the first child in Compiled.kids
+	count  int32 // number of children
+}
+
+// A Compiled grammar is an immutable form of a Grammar meant for analyses
+// and parser drivers. Every terminal and nonterminal has a dense Symbol
+// and the expressions are stored in flat arrays of nodes, so walking the
+// grammar never hashes a string or allocates.
+//
+// Groups are not compiled since they only change how an expression is
+// written; the node for a group is the node for its body.
+type Compiled struct {
+	terminals      []string
+	nonterminals   []string
+	terminalIDs    map[string]Symbol
+	nonterminalIDs map[string]Symbol
+	prods          []*Production // by nonterminal; nil if not defined
+	roots          []NodeID      // by nonterminal
+	nodes          []Node
+	kids           []NodeID
+	exprs          []Expression // by node
+}
+
+// Compile returns the compiled form of the grammar.
+//
+// Nonterminals are numbered in the order their productions appear in the
+// source, followed by any nonterminals that are referenced but not defined.
+// Terminals are numbered in the order they are first referenced.
+// Lexical productions, which are named by a TERMINAL, are not compiled:
+// the terminals they define are terminals of the compiled grammar.
+// It returns an error if the grammar contains an expression that
+// could not be parsed.
+//
+// The compiled form keeps a copy of the grammar, so later changes
+// to the grammar do not change it.
+func Compile(grammar Grammar) (*Compiled, error) {
+	grammar = Clone(grammar)
+	c := &Compiled{
+		terminalIDs:    make(map[string]Symbol),
+		nonterminalIDs: make(map[string]Symbol),
+	}
+	var prods []*Production
+	for _, prod := range Productions(grammar) {
+		if !isLexical(prod) {
+			prods = append(prods, prod)
+		}
+	}
+	for _, prod := range prods {
+		c.nonterminal(prod.Name.String())
+		c.prods[len(c.prods)-1] = prod
+	}
+	var errs errorList
+	for i, prod := range prods {
+		c.roots[i] = c.compile(prod.Expr, &errs)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return c, nil
+}
+
+// nonterminal returns the symbol for the name, adding it if needed.
+func (c *Compiled) nonterminal(name string) Symbol {
+	if sym, ok := c.nonterminalIDs[name]; ok {
+		return sym
+	}
+	sym := Symbol(len(c.nonterminals))
+	c.nonterminals = append(c.nonterminals, name)
+	c.nonterminalIDs[name] = sym
+	c.prods = append(c.prods, nil)
+	c.roots = append(c.roots, NoNode)
+	return sym
+}
+
+// terminal returns the symbol for the name, adding it if needed.
+func (c *Compiled) terminal(name string) Symbol {
+	if sym, ok := c.terminalIDs[name]; ok {
+		return sym
+	}
+	sym := Symbol(len(c.terminals))
+	c.terminals = append(c.terminals, name)
+	c.terminalIDs[name] = sym
+	return sym
+}
+
+func (c *Compiled) compile(x Expression, errs *errorList) NodeID {
+	switch x := x.(type) {
+	case nil:
+		return NoNode
+	case *Group:
+		return c.compile(x.Body, errs)
+	case *Bad:
+		*errs = append(*errs, fmt.Errorf("%d: %v", x.Pos(), x.err))
+		return NoNode
+	}
+
+	id := NodeID(len(c.nodes))
+	c.nodes = append(c.nodes, Node{Pos: Position(x)})
+	c.exprs = append(c.exprs, x)
+	var list []Expression
+	switch x := x.(type) {
+	case Alternative:
+		c.nodes[id].Kind, list = AlternativeNode, x
+	case Sequence:
+		c.nodes[id].Kind, list = SequenceNode, x
+	case *Name:
+		c.nodes[id].Kind, c.nodes[id].Symbol = NonTerminalNode, c.nonterminal(x.String())
+	case *Literal:
+		c.nodes[id].Kind, c.nodes[id].Symbol = TerminalNode, c.terminal(x.String())
+	case *Option:
+		c.nodes[id].Kind, list = OptionNode, []Expression{x.Body}
+	case *Repetition:
+		c.nodes[id].Kind, list = RepetitionNode, []Expression{x.Body}
+	default:
+		panic(fmt.Sprintf("internal error: unexpected type %T", x))
+	}
+	if len(list) == 0 {
+		return id
+	}
+
+	// children are compiled first, then stored together
+	kids := make([]NodeID, len(list))
+	for i, e := range list {
+		kids[i] = c.compile(e, errs)
+	}
+	c.nodes[id].first, c.nodes[id].count = int32(len(c.kids)), int32(len(kids))
+	c.kids = append(c.kids, kids...)
+	return id
+}
+
+// NumTerminals returns the number of terminals.
+func (c *Compiled) NumTerminals() int { return len(c.terminals) }
+
+// NumNonTerminals returns the number of nonterminals, including undefined ones.
+func (c *Compiled) NumNonTerminals() int { return len(c.nonterminals) }
+
+// NumNodes returns the number of nodes.
+func (c *Compiled) NumNodes() int { return len(c.nodes) }
+
+// Terminal returns the name of the terminal.
+func (c *Compiled) Terminal(sym Symbol) string { return c.terminals[sym] }
+
+// NonTerminal returns the name of the nonterminal.
+func (c *Compiled) NonTerminal(sym Symbol) string { return c.nonterminals[sym] }
+
+// TerminalID returns the symbol for the named terminal.
+func (c *Compiled) TerminalID(name string) (Symbol, bool) {
+	sym, ok := c.terminalIDs[name]
+	return sym, ok
+}
+
+// NonTerminalID returns the symbol for the named nonterminal.
+func (c *Compiled) NonTerminalID(name string) (Symbol, bool) {
+	sym, ok := c.nonterminalIDs[name]
+	return sym, ok
+}
+
+// Defined returns true if the nonterminal has a production.
+func (c *Compiled) Defined(sym Symbol) bool { return c.prods[sym] != nil }
+
+// Production returns the production for the nonterminal, or nil if it is not defined.
+// The production is a copy made by Compile and must not be modified.
+func (c *Compiled) Production(sym Symbol) *Production { return c.prods[sym] }
+
+// Root returns the node for the expression of the nonterminal's production.
+// It returns NoNode if the expression is empty or the nonterminal is not defined.
+func (c *Compiled) Root(sym Symbol) NodeID { return c.roots[sym] }
+
+// Node returns the node.
+func (c *Compiled) Node(id NodeID) Node { return c.nodes[id] }
+
+// Children returns the children of the node.
+// The slice is shared and must not be modified.
+func (c *Compiled) Children(id NodeID) []NodeID {
+	n := c.nodes[id]
+	return c.kids[n.first : n.first+n.count : n.first+n.count]
+}
+
+// Expr returns the expression the node was compiled from.
+// The expression is a copy made by Compile and must not be modified.
+func (c *Compiled) Expr(id NodeID) Expression { return c.exprs[id] }
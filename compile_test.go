@@ -0,0 +1,157 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	grammar, errs := Parse([]byte(`
+		program = song .
+		song = { note } [ Fine ] .
+		note = Do | (Re Mi) | ti .
+		rest = .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	c, err := Compile(grammar)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	var names []string
+	for i := 0; i < c.NumNonTerminals(); i++ {
+		sym := Symbol(i)
+		names = append(names, fmt.Sprintf("%s:%v", c.NonTerminal(sym), c.Defined(sym)))
+	}
+	if got, want := strings.Join(names, " "), "program:true song:true note:true rest:true ti:false"; got != want {
+		t.Errorf("nonterminals: want %q, got %q", want, got)
+	}
+	var terms []string
+	for i := 0; i < c.NumTerminals(); i++ {
+		terms = append(terms, c.Terminal(Symbol(i)))
+	}
+	if got, want := strings.Join(terms, " "), "Fine Do Re Mi"; got != want {
+		t.Errorf("terminals: want %q, got %q", want, got)
+	}
+	if sym, _ := c.NonTerminalID("rest"); c.Root(sym) != NoNode {
+		t.Errorf("rest: want NoNode, got %d", c.Root(sym))
+	}
+
+	// the group in note is not compiled
+	sym, _ := c.NonTerminalID("note")
+	root := c.Node(c.Root(sym))
+	if root.Kind != AlternativeNode || len(c.Children(c.Root(sym))) != 3 {
+		t.Fatalf("note: want alternative with 3 children, got %s with %d", root.Kind, len(c.Children(c.Root(sym))))
+	}
+	if kid := c.Node(c.Children(c.Root(sym))[1]); kid.Kind != SequenceNode || kid.Pos.Line != 4 {
+		t.Errorf("note: want sequence on line 4, got %s on line %d", kid.Kind, kid.Pos.Line)
+	}
+}
+
+func TestCompileCopies(t *testing.T) {
+	grammar, errs := Parse([]byte(`s = A B | C .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	c, err := Compile(grammar)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	// changing the grammar after compiling it does not change the compiled form
+	grammar["s"].Expr.(Alternative)[1] = NewLiteral(Position(grammar["s"].Expr), "D")
+	grammar["s"].Doc = []*Comment{{}}
+	sym, _ := c.NonTerminalID("s")
+	if got := ExprString(c.Production(sym).Expr); got != "A B | C" {
+		t.Errorf("Production: want %q, got %q", "A B | C", got)
+	}
+	if got := ExprString(c.Expr(c.Children(c.Root(sym))[1])); got != "C" {
+		t.Errorf("Expr: want %q, got %q", "C", got)
+	}
+	if c.Production(sym).Doc != nil {
+		t.Errorf("Production: want no comments, got %d", len(c.Production(sym).Doc))
+	}
+}
+
+func TestCompileLexical(t *testing.T) {
+	grammar, errs := Parse([]byte(`
+		program = Number { Comma Number } .
+		Number = Digit { Digit } .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	c, err := Compile(grammar)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	// the lexical production defines a terminal, not a nonterminal
+	if c.NumNonTerminals() != 1 || c.NonTerminal(0) != "program" {
+		t.Errorf("want program as the only nonterminal, got %d", c.NumNonTerminals())
+	}
+	if _, ok := c.NonTerminalID("Number"); ok {
+		t.Errorf("Number should not be a nonterminal")
+	}
+	if _, ok := c.TerminalID("Number"); !ok {
+		t.Errorf("Number should be a terminal")
+	}
+	if _, ok := c.TerminalID("Digit"); ok {
+		t.Errorf("Digit is only used by a lexical production and should not be compiled")
+	}
+}
+
+func TestCompileLua(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar, errs := Parse(input)
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	c, err := Compile(grammar)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if c.NumNonTerminals() != len(grammar) {
+		t.Errorf("want %d nonterminals, got %d", len(grammar), c.NumNonTerminals())
+	}
+	// every node is the expression it was compiled from
+	for id := NodeID(0); int(id) < c.NumNodes(); id++ {
+		if Position(c.Expr(id)) != c.Node(id).Pos {
+			t.Errorf("%d: position does not match expression", id)
+		}
+	}
+	if _, err = Compile(Grammar{"a": &Production{Name: grammar["chunk"].Name, Expr: &Bad{}}}); err == nil {
+		t.Errorf("Compile should have failed")
+	}
+}
+
+func TestBitset(t *testing.T) {
+	a, b := NewBitset(130), NewBitset(130)
+	for _, i := range []int{0, 63, 64, 129} {
+		a.Set(i)
+	}
+	b.Set(64)
+	b.Set(5)
+	if !a.Intersects(b) || a.Len() != 4 || !b.Has(5) || b.Has(6) {
+		t.Errorf("set operations failed")
+	}
+	if !a.Union(b) || a.Union(b) {
+		t.Errorf("Union should change the set only once")
+	}
+	a.Clear(0)
+	if got := fmt.Sprint(a.Elements()); got != "[5 63 64 129]" {
+		t.Errorf("Elements: want [5 63 64 129], got %s", got)
+	}
+	if got := fmt.Sprint(a.Intersection(b).Elements()); got != "[5 64]" {
+		t.Errorf("Intersection: want [5 64], got %s", got)
+	}
+	if !a.Clone().Equal(a) || a.Equal(b) || !NewBitset(3).IsEmpty() {
+		t.Errorf("Equal failed")
+	}
+}
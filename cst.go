@@ -0,0 +1,146 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"bytes"
+	"github.com/mdhender/ebnf/scanners"
+	"github.com/mdhender/ebnf/tokens"
+	"strings"
+)
+
+// A CST is a lossless concrete syntax tree for a grammar.
+// Every token records the spaces and comments around it,
+// so Bytes reproduces the input exactly.
+//
+// Grammar is the abstract syntax tree built from the same tokens.
+// It may be edited: productions may be changed, replaced, removed,
+// or added, and Bytes will apply those edits to the input while
+// keeping the layout of everything that was not changed.
+type CST struct {
+	Grammar Grammar
+
+	tokens []*tokens.Token
+	prods  map[*tokens.Token]*Production // productions as parsed, indexed by name token
+	text   map[*Production]string        // canonical text of the productions as parsed
+}
+
+// ParseCST parses the input like Parse but keeps the trivia.
+// Comments are kept in the trivia and are not attached to the productions.
+func ParseCST(input []byte) (*CST, []error) {
+	toks := scanners.ScanMode(input, scanners.KeepTrivia)
+
+	var p parser
+	c := &CST{
+		Grammar: p.parse(toks),
+		tokens:  toks,
+		prods:   make(map[*tokens.Token]*Production),
+		text:    make(map[*Production]string),
+	}
+	for _, prod := range c.Grammar {
+		if prod.end == nil || prod.end.Kind != tokens.TERMINATOR {
+			// could not find the end of the production, so it is never rewritten
+			continue
+		} else if text, err := productionText(prod); err == nil {
+			c.prods[prod.Name.tok], c.text[prod] = prod, text
+		}
+	}
+	return c, p.errors
+}
+
+// Tokens returns all the tokens in the input, including the end of input token.
+// The tokens must not be modified.
+func (c *CST) Tokens() []*tokens.Token {
+	return c.tokens
+}
+
+// Bytes returns the input with the edits made to the Grammar applied.
+//
+// A production is copied from the input unless its canonical text has changed.
+// A changed production is written in canonical form, keeping the trivia
+// before its name and after its terminator. A production that was removed
+// from the Grammar is dropped along with that trivia. Productions added
+// to the Grammar are written in canonical form after the last production.
+func (c *CST) Bytes() []byte {
+	var buf bytes.Buffer
+	seen := make(map[string]bool)
+	for i := 0; i < len(c.tokens); i++ {
+		tok := c.tokens[i]
+		if tok.Kind == tokens.EOF {
+			c.added(&buf, seen)
+			buf.Write(tok.Leading)
+			break
+		}
+
+		orig, ok := c.prods[tok]
+		if !ok {
+			writeToken(&buf, tok)
+			continue
+		}
+		name := orig.Name.String()
+		seen[name] = true
+
+		// find the end of the production
+		j := i
+		for c.tokens[j] != orig.end {
+			j++
+		}
+
+		prod, ok := c.Grammar[name]
+		if !ok {
+			// removed, along with its trivia
+			i = j
+			continue
+		}
+		text, err := productionText(prod)
+		if err != nil || text == c.text[orig] {
+			for ; i <= j; i++ {
+				writeToken(&buf, c.tokens[i])
+			}
+			i = j
+			continue
+		}
+		buf.Write(tok.Leading)
+		buf.WriteString(text)
+		buf.Write(orig.end.Trailing)
+		i = j
+	}
+	return buf.Bytes()
+}
+
+// added writes the productions that are not in the input.
+func (c *CST) added(buf *bytes.Buffer, seen map[string]bool) {
+	for _, prod := range Productions(c.Grammar) {
+		if seen[prod.Name.String()] {
+			continue
+		}
+		var p printer
+		p.production(prod, len(prod.Name.String()))
+		if p.err != nil {
+			continue
+		}
+		if buf.Len() != 0 {
+			if !bytes.HasSuffix(buf.Bytes(), []byte{'\n'}) {
+				buf.WriteByte('\n')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.Write(p.Bytes())
+	}
+}
+
+// productionText returns the canonical text of the production
+// without any comments or the final new line.
+func productionText(prod *Production) (string, error) {
+	var p printer
+	p.production(&Production{Name: prod.Name, Expr: prod.Expr}, len(prod.Name.String()))
+	return strings.TrimSuffix(p.String(), "\n"), p.err
+}
+
+func writeToken(buf *bytes.Buffer, tok *tokens.Token) {
+	buf.Write(tok.Leading)
+	buf.Write(tok.Text)
+	buf.Write(tok.Trailing)
+}
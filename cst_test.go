@@ -0,0 +1,54 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCSTRoundTrip(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range append(goodGrammars, string(input), "a = B . ; x\n\n\n  b = ( C\n| D ) . ; y\n; z") {
+		c, errs := ParseCST([]byte(src))
+		if errs != nil {
+			t.Errorf("ParseCST(%q) failed: %v", src, errs)
+			continue
+		}
+		if got := string(c.Bytes()); got != src {
+			t.Errorf("Bytes: want %q, got %q", src, got)
+		}
+	}
+}
+
+func TestCSTEdits(t *testing.T) {
+	src := "; header\n\nprogram = song . ; keep\n\n; about songs\nsong = { note } .\n\nnote =   Do|Re . ; notes\n\n; end\n"
+	c, errs := ParseCST([]byte(src))
+	if errs != nil {
+		t.Fatalf("ParseCST failed: %v", errs)
+	}
+
+	// no edits, no changes
+	if got := string(c.Bytes()); got != src {
+		t.Errorf("want %q, got %q", src, got)
+	}
+
+	// reordering alternatives changes the text
+	note := c.Grammar["note"].Expr.(Alternative)
+	note[0], note[1] = note[1], note[0]
+	delete(c.Grammar, "song")
+	b := NewBuilder()
+	b.Prod("rest").Seq(b.T("Rest"), b.Opt(b.N("note")))
+	added, _ := b.Grammar()
+	c.Grammar["rest"] = added["rest"]
+
+	want := "; header\n\nprogram = song . ; keep\n\nnote = Re\n     | Do . ; notes\n\nrest = Rest [note] .\n\n; end\n"
+	if got := string(c.Bytes()); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -7,7 +7,7 @@
 // The input is text ([]byte) satisfying the following grammar (represented itself in EBNF):
 //
 //	grammar     = production { production } .
-//	production  = NONTERMINAL EQ [ expression ] TERMINATOR .
+//	production  = ( NONTERMINAL | TERMINAL ) EQ [ expression ] TERMINATOR .
 //	expression  = sequence { OR sequence } .
 //	sequence    = term { term } .
 //	term        = NONTERMINAL | TERMINAL | group | option | repetition .
@@ -17,6 +17,8 @@
 //
 // A NONTERMINAL denotes a non-terminal production.
 // A TERMINAL denotes a token returned from the scanner
+// or defined by a lexical production, a production named by a TERMINAL.
+// Lexical productions may only reference terminals.
 //
 //		NONTERMINAL      = LOWERLETTER { LETTER | DIGIT | UNDERSCORE }
 //		TERMINAL         = UPPERLETTER { LETTER | DIGIT | UNDERSCORE }
@@ -28,9 +30,12 @@
 //		END_OPTION       = "]"
 //		START_REPETITION = "{"
 //		END_REPETITION   = "}"
-//	 COMMENT          = "//" ... EOL
+//		COMMENT          = ";" ... EOL
 //		TERMINATOR       = "."
 //
 // The scanner treats spaces, invalid runes, and comments as delimiters
-// that separate tokens.
+// that separate tokens. The parser attaches comments to the nearest
+// production so that Fprint can write them back out. A comment like
+// "; ebnf:start chunk stat" declares the start productions of the grammar;
+// see StartSymbols.
 package ebnf
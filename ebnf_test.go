@@ -6,8 +6,11 @@
 package ebnf
 
 import (
+	"fmt"
 	"github.com/mdhender/ebnf/scanners"
 	"github.com/mdhender/ebnf/tokens"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -22,6 +25,9 @@ var goodGrammars = []string{
 	 note = Do | (Re Mi | Fa | So La) | ti .
 	 ti = Ti .`,
 	`program=song.song={note}.note=Do|(Re Mi|Fa|So La)|ti.ti=Ti.`,
+	`program = Number { Comma Number } .
+	 Number = Digit { Digit } [ Dot Digits ] .
+	 Digits = Digit { Digit } .`,
 }
 
 var badParse = []string{
@@ -48,6 +54,10 @@ var badVerify = []string{
 	`start = a B .`,
 	`program = A .
 	 a = A .`,
+	`program = A program .`,
+	`program = Number .
+	 Number = digit .
+	 digit = Zero | One .`,
 }
 
 func checkGood(t *testing.T, src string) {
@@ -119,3 +129,186 @@ func TestGrammars(t *testing.T) {
 		checkBadVerify(t, src)
 	}
 }
+
+func TestVerifyProductive(t *testing.T) {
+	grammar, errs := Parse([]byte(`
+		program = a | B [c] .
+		a = A a | a a | (b | c) A c .
+		b = B { b } c .
+		c = C c .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	var got []string
+	for _, err := range Verify(grammar, "program") {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`3: "a" is unproductive`,
+		`3: "a": alternative "A a" requires "a"`,
+		`3: "a": alternative "a a" requires "a"`,
+		`3: "a": alternative "(b | c) A c" requires "b", "c"`,
+		`4: "b" is unproductive`,
+		`4: "b": alternative "B {b} c" requires "c"`,
+		`5: "c" is unproductive`,
+		`5: "c": alternative "C c" requires "c"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestVerifyLexical(t *testing.T) {
+	for i, tc := range []struct {
+		input string
+		want  []string
+	}{
+		// syntactic productions may reference terminals and nonterminals
+		{`program = Number | list .
+		  list = LParen { program } RParen .
+		  Number = Digit { Digit } .`, nil},
+		// lexical productions may reference only terminals
+		{`program = Number .
+		  Number = Digit { digit | Digit } .
+		  digit = Zero .`, []string{
+			`2: lexical production "Number" references nonterminal "digit"`,
+		}},
+		{`program = Word .
+		  Word = letter .`, []string{
+			`2: missing production "letter"`,
+			`2: lexical production "Word" references nonterminal "letter"`,
+		}},
+		// lexical productions must be reached like any other
+		{`program = A .
+		  Number = Digit .`, []string{`2: "Number" is unreachable`}},
+		{`program = Number .
+		  Number = Digit Number .`, []string{
+			`1: "program" is unproductive`,
+			`1: "program": alternative "Number" requires "Number"`,
+			`2: "Number" is unproductive`,
+			`2: "Number": alternative "Digit Number" requires "Number"`,
+		}},
+	} {
+		grammar, errs := Parse([]byte(tc.input))
+		if errs != nil {
+			t.Fatalf("%d: Parse failed: %v", i+1, errs)
+		}
+		var got []string
+		for _, err := range Verify(grammar, "program") {
+			got = append(got, err.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%d: want\n%s\ngot\n%s", i+1, strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestVerifyStart(t *testing.T) {
+	input := `chunk = { stat } .
+		stat = exp Semi | Do chunk End .
+		exp = Name | Number .
+		Number = Digit { Digit } .
+		repl = stat .`
+	for i, tc := range []struct {
+		input string
+		start []string
+		want  []string
+	}{
+		{input, []string{"chunk"}, []string{`5: "repl" is unreachable`}},
+		{input, []string{"exp"}, []string{`1: "chunk" is unreachable`, `2: "stat" is unreachable`, `5: "repl" is unreachable`}},
+		{input, []string{"exp", "repl"}, nil},
+		// a missing start production does not keep the others from being checked
+		{input, []string{"chunk", "program"}, []string{`0: no start production "program"`, `5: "repl" is unreachable`}},
+		{input, []string{"program", "exp", "main", "repl"}, []string{`0: no start production "main"`, `0: no start production "program"`}},
+		{input, []string{"program"}, []string{`0: no start production "program"`}},
+		{"s = a .\nt = B .", []string{"s", "x", "t"}, []string{`0: no start production "x"`, `1: missing production "a"`}},
+		{input, nil, []string{`0: no start production`}},
+		// start productions may be declared in the grammar
+		{"; ebnf:start chunk\n" + input + "\n; ebnf:start repl", nil, nil},
+		{"; ebnf:start exp\n" + input, nil, []string{`2: "chunk" is unreachable`, `3: "stat" is unreachable`, `6: "repl" is unreachable`}},
+		// but not when they are given
+		{"; ebnf:start repl\n" + input, []string{"chunk"}, []string{`6: "repl" is unreachable`}},
+	} {
+		grammar, errs := Parse([]byte(tc.input))
+		if errs != nil {
+			t.Fatalf("%d: Parse failed: %v", i+1, errs)
+		}
+		var got []string
+		for _, err := range Verify(grammar, tc.start...) {
+			got = append(got, err.Error())
+		}
+		sort.Strings(got)
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%d: want\n%s\ngot\n%s", i+1, strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestStartSymbols(t *testing.T) {
+	grammar, errs := Parse([]byte(`; ebnf:start chunk
+		; ebnf:start  stat exp
+		chunk = { stat } . ; ebnf:start repl chunk
+		stat = exp .
+		; not a declaration: ebnf:start stat
+		exp = Name .
+		; ebnf:start
+		repl = stat .
+		; ebnf:start last`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	want := []string{"chunk", "stat", "exp", "repl", "last"}
+	if got := StartSymbols(grammar); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestReachability(t *testing.T) {
+	grammar, errs := Parse([]byte(`chunk = { stat } .
+		stat = exp Semi | Do chunk End .
+		exp = Name | Number .
+		Number = Digit { Digit } .
+		repl = stat .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	reach := Reachability(grammar, "chunk", "exp", "repl", "program")
+	for _, tc := range []struct {
+		root string
+		want []string
+	}{
+		{"chunk", []string{"chunk", "stat", "exp", "Number"}},
+		{"exp", []string{"exp", "Number"}},
+		{"repl", []string{"chunk", "stat", "exp", "Number", "repl"}},
+		{"program", nil},
+	} {
+		if got, ok := reach[tc.root]; !ok || strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("%s: want %v, got %v", tc.root, tc.want, got)
+		}
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	grammar, errs := Parse([]byte(`; ebnf:start s
+		; an ordinary comment
+		s = A ; lint:ignore redundant
+		  | A .
+		t = B . ; lint:ignore max-alternatives
+		; lint:file-ignore terminal-case`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	var got []string
+	for _, d := range Directives(grammar) {
+		got = append(got, fmt.Sprintf("%d %q %s %v", d.Comment.Pos(), d.Production, d.Key, d.Args))
+	}
+	want := []string{
+		`1 "s" ebnf:start [s]`,
+		`3 "s" lint:ignore [redundant]`,
+		`5 "t" lint:ignore [max-alternatives]`,
+		`6 "" lint:file-ignore [terminal-case]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Equal reports whether the two grammars define the same productions
+// with structurally equal expressions.
+//
+// Positions and comments are ignored, as are groups that do not change
+// the structure: "a (b c)" is equal to "a b c" and "a | (b | c)" is
+// equal to "a | b | c". The order of the alternatives in an Alternative
+// does not matter, but the number of times each one appears does.
+func Equal(a, b Grammar) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var k keyer
+	for name, x := range a {
+		y, ok := b[name]
+		if !ok || k.key(x.Expr) != k.key(y.Expr) {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualExpr reports whether the two expressions are structurally equal.
+// It uses the same rules as Equal.
+func EqualExpr(x, y Expression) bool {
+	var k keyer
+	return k.key(x) == k.key(y)
+}
+
+// keyer returns a canonical key for an expression.
+// Two expressions are structurally equal if they have the same key.
+type keyer struct {
+	rename map[string]string // names to replace, may be nil
+}
+
+func (k keyer) key(x Expression) string {
+	switch x := x.(type) {
+	case nil:
+		return ""
+	case Alternative:
+		return k.alternatives(x)
+	case Sequence:
+		var list []string
+		for _, e := range Elements(x) {
+			list = append(list, k.key(e))
+		}
+		if len(list) == 1 {
+			return list[0]
+		}
+		return "(" + strings.Join(list, " ") + ")"
+	case *Name:
+		if name, ok := k.rename[x.String()]; ok {
+			return name
+		}
+		return x.String()
+	case *Literal:
+		return x.String()
+	case *Group:
+		return k.key(x.Body)
+	case *Option:
+		return "[" + k.key(x.Body) + "]"
+	case *Repetition:
+		return "{" + k.key(x.Body) + "}"
+	case *Bad:
+		return fmt.Sprintf("!%v", x.err)
+	}
+	panic(fmt.Sprintf("internal error: unexpected type %T", x))
+}
+
+// alternatives returns the key for the top level alternatives of x.
+func (k keyer) alternatives(x Expression) string {
+	list := k.keys(x)
+	if len(list) == 1 {
+		return list[0]
+	}
+	sort.Strings(list)
+	return "(" + strings.Join(list, "|") + ")"
+}
+
+// keys returns the keys of the top level alternatives of x.
+func (k keyer) keys(x Expression) (list []string) {
+	for _, alt := range Alternatives(x) {
+		list = append(list, k.key(sequence(alt)))
+	}
+	return list
+}
+
+// A ChangeKind is the kind of difference between two grammars.
+type ChangeKind int
+
+const (
+	Added   ChangeKind = iota // production only in the new grammar
+	Removed                   // production only in the old grammar
+	Renamed                   // production has a new name but the same expression
+	Changed                   // production has different alternatives
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Renamed:
+		return "renamed"
+	case Changed:
+		return "changed"
+	}
+	panic(fmt.Sprintf("assert(kind != %d)", k))
+}
+
+// A Change is a difference between two grammars found by Diff.
+type Change struct {
+	Kind     ChangeKind
+	Name     string      // name of the production; the old name if it was renamed
+	NewName  string      // new name of a renamed production
+	Old, New *Production // the production in each grammar, nil if it is not there
+	// Removed and Added are the top level alternatives that are only in
+	// the old or the new production of a changed production.
+	Removed, Added []Expression
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%d: %s: added", c.New.Pos(), c.Name)
+	case Removed:
+		return fmt.Sprintf("%d: %s: removed", c.Old.Pos(), c.Name)
+	case Renamed:
+		return fmt.Sprintf("%d: %s: renamed to %s", c.Old.Pos(), c.Name, c.NewName)
+	}
+	return fmt.Sprintf("%d: %s: %d alternatives removed, %d added", c.Old.Pos(), c.Name, len(c.Removed), len(c.Added))
+}
+
+// Diff returns the differences in the language defined by the old and new grammars.
+//
+// Expressions are compared with the rules of Equal, so changes to layout,
+// comments, redundant groups, or the order of alternatives are not reported.
+// A production that is only in the old grammar is reported as renamed when
+// a production that is only in the new grammar has the same expression;
+// references to renamed productions are not reported as changes.
+//
+// Changes are sorted by kind and then by name.
+func Diff(old, new Grammar) []Change {
+	var removed, added []string
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	// find renamed productions. the key of a production is computed with
+	// references to itself and to productions already found to be renamed
+	// replaced by their new names. repeat until nothing more is found,
+	// since finding one rename can make the keys of others match.
+	rename := make(map[string]string)
+	for found := true; found; {
+		found = false
+		for _, from := range removed {
+			if _, ok := rename[from]; ok {
+				continue
+			}
+			for _, to := range added {
+				if isRenameTarget(rename, to) {
+					continue
+				}
+				names := map[string]string{from: to}
+				for k, v := range rename {
+					names[k] = v
+				}
+				if (keyer{rename: names}).key(old[from].Expr) == (keyer{}).key(new[to].Expr) {
+					rename[from], found = to, true
+					break
+				}
+			}
+		}
+	}
+
+	var changes []Change
+	for _, name := range added {
+		if !isRenameTarget(rename, name) {
+			changes = append(changes, Change{Kind: Added, Name: name, New: new[name]})
+		}
+	}
+	for _, name := range removed {
+		if _, ok := rename[name]; !ok {
+			changes = append(changes, Change{Kind: Removed, Name: name, Old: old[name]})
+		}
+	}
+	for _, name := range removed {
+		if to, ok := rename[name]; ok {
+			changes = append(changes, Change{Kind: Renamed, Name: name, NewName: to, Old: old[name], New: new[to]})
+		}
+	}
+
+	var names []string
+	for name := range old {
+		if _, ok := new[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		before, after := old[name], new[name]
+		oldKeys, newKeys := (keyer{rename: rename}).keys(before.Expr), (keyer{}).keys(after.Expr)
+		c := Change{Kind: Changed, Name: name, Old: before, New: after}
+		c.Removed = missing(Alternatives(before.Expr), oldKeys, newKeys)
+		c.Added = missing(Alternatives(after.Expr), newKeys, oldKeys)
+		if len(c.Removed) != 0 || len(c.Added) != 0 {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func isRenameTarget(rename map[string]string, name string) bool {
+	for _, to := range rename {
+		if to == name {
+			return true
+		}
+	}
+	return false
+}
+
+// missing returns the alternatives whose keys are not matched by a key in other.
+// Each key in other matches at most one alternative.
+func missing(alternatives [][]Expression, keys, other []string) (list []Expression) {
+	count := make(map[string]int)
+	for _, key := range other {
+		count[key]++
+	}
+	for i, key := range keys {
+		if count[key] > 0 {
+			count[key]--
+			continue
+		}
+		list = append(list, sequence(alternatives[i]))
+	}
+	return list
+}
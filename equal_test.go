@@ -0,0 +1,88 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	for _, tc := range []struct {
+		id    int
+		a, b  string
+		equal bool
+	}{
+		{1, "a = B C | D .", "; comment\na = D\n  | B C .", true},
+		{2, "a = B (C D) .", "a = B C D .", true},
+		{3, "a = B | (C | D) .", "a = D | C | B .", true},
+		{4, "a = (B | C) D .", "a = B | C D .", false},
+		{5, "a = B | B .", "a = B .", false},
+		{6, "a = [B] .", "a = {B} .", false},
+		{7, "a = B .", "a = B . b = C .", false},
+		{8, "a = .", "a = .", true},
+		{9, "a = [(B | C)] .", "a = [C | B] .", true},
+	} {
+		a, errs := Parse([]byte(tc.a))
+		if errs != nil {
+			t.Fatalf("%d: Parse failed: %v", tc.id, errs)
+		}
+		b, errs := Parse([]byte(tc.b))
+		if errs != nil {
+			t.Fatalf("%d: Parse failed: %v", tc.id, errs)
+		}
+		if got := Equal(a, b); got != tc.equal {
+			t.Errorf("%d: Equal: want %v, got %v", tc.id, tc.equal, got)
+		}
+		if got := Equal(b, a); got != tc.equal {
+			t.Errorf("%d: Equal: want %v, got %v", tc.id, tc.equal, got)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old, errs := Parse([]byte(`
+		program = song | dance .
+		song = { note } .
+		note = Do | Re | Mi .
+		dance = Step { Step } .
+		rest = Rest .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	new, errs := Parse([]byte(`
+		program = song
+		        | ballet .
+		song = {note} .
+		note = Mi | Re | Fa .
+		ballet = Step {Step} .
+		coda = Fine .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	var got []string
+	for _, c := range Diff(old, new) {
+		s := fmt.Sprintf("%s %s %s", c.Kind, c.Name, c.NewName)
+		for _, x := range c.Removed {
+			s += " -" + ExprString(x)
+		}
+		for _, x := range c.Added {
+			s += " +" + ExprString(x)
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"added coda ",
+		"removed rest ",
+		"renamed dance ballet",
+		"changed note  -Do +Fa",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Diff:\nwant %q\ngot  %q", want, got)
+	}
+	if changes := Diff(old, old); changes != nil {
+		t.Errorf("Diff(old, old): want no changes, got %v", changes)
+	}
+}
@@ -8,6 +8,8 @@ package ebnf
 import (
 	"fmt"
 	"github.com/mdhender/ebnf/tokens"
+	"sort"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -15,15 +17,87 @@ import (
 // The map is indexed by production name.
 type Grammar map[string]*Production
 
-// ----------------------------------------------------------------------------
-// Grammar verification
+// Productions returns the productions of the grammar in source order.
+func Productions(grammar Grammar) []*Production {
+	var prods []*Production
+	for _, prod := range grammar {
+		prods = append(prods, prod)
+	}
+	sort.Slice(prods, func(i, j int) bool {
+		a, b := prods[i].Name.tok.Pos, prods[j].Name.tok.Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		} else if a.Col != b.Col {
+			return a.Col < b.Col
+		}
+		return prods[i].Name.String() < prods[j].Name.String()
+	})
+	return prods
+}
+
+// Alternatives returns the top level alternatives of the expression,
+// looking through groups, each as a list of elements.
+func Alternatives(x Expression) (list [][]Expression) {
+	switch x := x.(type) {
+	case *Group:
+		return Alternatives(x.Body)
+	case Alternative:
+		for _, e := range x {
+			list = append(list, Alternatives(e)...)
+		}
+		return list
+	}
+	return [][]Expression{Elements(x)}
+}
+
+// Elements returns the elements of a sequence, looking through groups
+// and nested sequences. An empty expression has no elements.
+func Elements(x Expression) (list []Expression) {
+	switch x := x.(type) {
+	case nil:
+		return nil
+	case *Group:
+		if _, ok := x.Body.(Alternative); !ok {
+			return Elements(x.Body)
+		}
+	case Sequence:
+		for _, e := range x {
+			list = append(list, Elements(e)...)
+		}
+		return list
+	}
+	return []Expression{x}
+}
+
+// sequence returns the elements as a single expression: nil if there
+// are none, the element if there is one, and a Sequence otherwise.
+func sequence(list []Expression) Expression {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return Sequence(list)
+}
 
-func isNonTerminal(tok *tokens.Token) bool {
-	return tok.Kind == tokens.NONTERMINAL
+// define adds the production to the grammar.
+// It returns an error if a production with the same name is already defined.
+func (g Grammar) define(prod *Production) error {
+	name := prod.Name.String()
+	if def, found := g[name]; found {
+		return fmt.Errorf("%d: %s: defined line %d", prod.Name.tok.Line(), def.Name.String(), def.Name.tok.Line())
+	}
+	g[name] = prod
+	return nil
 }
 
-func isTerminal(tok *tokens.Token) bool {
-	return tok.Kind == tokens.TERMINAL
+// ----------------------------------------------------------------------------
+// Grammar verification
+
+// isLexical returns true if the production is named by a TERMINAL.
+func isLexical(prod *Production) bool {
+	return prod.Name.tok.Kind == tokens.TERMINAL
 }
 
 type verifier struct {
@@ -55,38 +129,48 @@ func (v *verifier) verifyChar(x *tokens.Token) rune {
 	return ch
 }
 
-func (v *verifier) verifyExpr(expr Expression, lexical bool) {
+// verifyExpr checks the expression of the production.
+//
+// A production named by a TERMINAL is lexical: it defines the terminal.
+// A lexical production may only reference terminals, which are either
+// defined by other lexical productions or returned by the scanner.
+// A production named by a NONTERMINAL is syntactic and may reference
+// both terminals and nonterminals.
+func (v *verifier) verifyExpr(prod *Production, expr Expression) {
 	switch x := expr.(type) {
 	case nil:
 		// empty expression
 	case Alternative:
 		for _, e := range x {
-			v.verifyExpr(e, lexical)
+			v.verifyExpr(prod, e)
 		}
 	case Sequence:
 		for _, e := range x {
-			v.verifyExpr(e, lexical)
+			v.verifyExpr(prod, e)
 		}
 	case *Name:
 		// a production with this name must exist;
 		// add it to the worklist if not yet processed
-		if prod, found := v.grammar[x.String()]; found {
-			v.push(prod)
+		if def, found := v.grammar[x.String()]; found {
+			v.push(def)
 		} else {
 			v.error("%d: missing production %q", x.tok.Line(), x.String())
 		}
-		// within a lexical production references to non-lexical productions are invalid
-		if lexical && isTerminal(x.tok) {
-			v.error("%d: reference to non-lexical production %q", x.tok.Line(), x.String())
+		// within a lexical production references to syntactic productions are invalid
+		if isLexical(prod) {
+			v.error("%d: lexical production %q references nonterminal %q", x.tok.Line(), prod.Name.String(), x.String())
 		}
 	case *Literal:
-		// nothing to do for now
+		// a terminal may be defined by a lexical production
+		if def, found := v.grammar[x.String()]; found {
+			v.push(def)
+		}
 	case *Group:
-		v.verifyExpr(x.Body, lexical)
+		v.verifyExpr(prod, x.Body)
 	case *Option:
-		v.verifyExpr(x.Body, lexical)
+		v.verifyExpr(prod, x.Body)
 	case *Repetition:
-		v.verifyExpr(x.Body, lexical)
+		v.verifyExpr(prod, x.Body)
 	case *Bad:
 		v.error("%d: %v", x.tok.Line(), x.err)
 	default:
@@ -94,21 +178,31 @@ func (v *verifier) verifyExpr(expr Expression, lexical bool) {
 	}
 }
 
-func (v *verifier) verify(grammar Grammar, start string) {
-	// find root production
-	root, found := grammar[start]
-	if !found {
-		v.error("%d: no start production %q", 0, start)
-		return
-	}
-
+func (v *verifier) verify(grammar Grammar, start []string) {
 	// initialize verifier
 	v.worklist = v.worklist[0:0]
 	v.reached = make(Grammar)
 	v.grammar = grammar
 
+	// find root productions
+	if len(start) == 0 {
+		v.error("%d: no start production", 0)
+		return
+	}
+	// report each missing root and go on with the others
+	for _, name := range start {
+		root, found := grammar[name]
+		if !found {
+			v.error("%d: no start production %q", 0, name)
+			continue
+		}
+		v.push(root)
+	}
+	if len(v.worklist) == 0 {
+		return
+	}
+
 	// work through the worklist
-	v.push(root)
 	for {
 		n := len(v.worklist) - 1
 		if n < 0 {
@@ -116,7 +210,7 @@ func (v *verifier) verify(grammar Grammar, start string) {
 		}
 		prod := v.worklist[n]
 		v.worklist = v.worklist[0:n]
-		v.verifyExpr(prod.Expr, isNonTerminal(prod.Name.tok))
+		v.verifyExpr(prod, prod.Expr)
 	}
 
 	// check if all productions were reached
@@ -129,14 +223,187 @@ func (v *verifier) verify(grammar Grammar, start string) {
 	}
 }
 
+// productive returns true if the expression can derive a finite sentence,
+// given the productions known to be productive. References to undefined
+// productions are reported elsewhere and are taken to be productive.
+func (v *verifier) productive(x Expression, productive map[string]bool) bool {
+	switch x := x.(type) {
+	case Alternative:
+		for _, e := range x {
+			if v.productive(e, productive) {
+				return true
+			}
+		}
+		return false
+	case Sequence:
+		for _, e := range x {
+			if !v.productive(e, productive) {
+				return false
+			}
+		}
+	case *Name:
+		_, defined := v.grammar[x.String()]
+		return productive[x.String()] || !defined
+	case *Literal:
+		// terminals not defined by a lexical production come from the scanner
+		_, defined := v.grammar[x.String()]
+		return productive[x.String()] || !defined
+	case *Group:
+		return v.productive(x.Body, productive)
+	}
+	// empty expressions, options, repetitions, and bad expressions
+	return true
+}
+
+// blockers returns the unproductive productions that keep an unproductive
+// expression from deriving a finite sentence, each once, in the order
+// they are referenced.
+func (v *verifier) blockers(x Expression, productive map[string]bool) []string {
+	var list []string
+	seen := make(map[string]bool)
+	var walk func(x Expression)
+	walk = func(x Expression) {
+		if v.productive(x, productive) {
+			return
+		}
+		switch x := x.(type) {
+		case Alternative:
+			for _, e := range x {
+				walk(e)
+			}
+		case Sequence:
+			for _, e := range x {
+				walk(e)
+			}
+		case *Name, *Literal:
+			if name := fmt.Sprintf("%q", x); !seen[name] {
+				seen[name], list = true, append(list, name)
+			}
+		case *Group:
+			walk(x.Body)
+		}
+	}
+	walk(x)
+	return list
+}
+
+// verifyProductive reports the productions that can not derive a finite
+// sentence, such as "a = A a .", and the alternatives that make them so.
+func (v *verifier) verifyProductive(grammar Grammar) {
+	v.grammar = grammar
+	productive := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for name, prod := range grammar {
+			if !productive[name] && v.productive(prod.Expr, productive) {
+				productive[name], changed = true, true
+			}
+		}
+	}
+	for _, prod := range Productions(grammar) {
+		name := prod.Name.String()
+		if productive[name] {
+			continue
+		}
+		v.error("%d: %q is unproductive", prod.Pos(), name)
+		for _, elements := range Alternatives(prod.Expr) {
+			alt := sequence(elements)
+			v.error("%d: %q: alternative %q requires %s", alt.Pos(), name, ExprString(alt), strings.Join(v.blockers(alt, productive), ", "))
+		}
+	}
+}
+
 // Verify checks that:
 //   - all productions used are defined
-//   - all productions defined are used when beginning at start
-//   - lexical productions refer only to other lexical productions
+//   - all productions defined are used when beginning at one of the start productions
+//   - lexical productions, which are named by a TERMINAL, reference only terminals
+//   - all productions can derive a finite sentence
 //
-// Position information is interpreted relative to the file set fset.
-func Verify(grammar Grammar, start string) []error {
+// If no start productions are given, the ones declared in the grammar
+// are used. See StartSymbols.
+func Verify(grammar Grammar, start ...string) []error {
+	if len(start) == 0 {
+		start = StartSymbols(grammar)
+	}
 	var v verifier
 	v.verify(grammar, start)
+	v.verifyProductive(grammar)
 	return v.errors
 }
+
+// A Directive is a comment that tells a tool something about the grammar,
+// written as
+//
+//	; key arg ...
+//
+// where the key has a prefix that names the tool, as in "ebnf:start"
+// or "lint:ignore".
+type Directive struct {
+	Production string // the production the comment is attached to; "" after the last production
+	Comment    *Comment
+	Key        string
+	Args       []string
+}
+
+// Directives returns the directives in the comments of the grammar, by
+// production in source order. Comments before a production or within its
+// expression are attached to it, as is a comment following it on the
+// same line. Comments after the last production are attached to none.
+func Directives(grammar Grammar) []Directive {
+	var list []Directive
+	add := func(prod string, c *Comment) {
+		fields := strings.Fields(strings.TrimPrefix(c.String(), ";"))
+		if len(fields) == 0 || !strings.Contains(fields[0], ":") {
+			return
+		}
+		list = append(list, Directive{Production: prod, Comment: c, Key: fields[0], Args: fields[1:]})
+	}
+	for _, prod := range Productions(grammar) {
+		for _, c := range prod.Doc {
+			add(prod.Name.String(), c)
+		}
+		if prod.LineComment != nil {
+			add(prod.Name.String(), prod.LineComment)
+		}
+		for _, c := range prod.Trailer {
+			add("", c)
+		}
+	}
+	return list
+}
+
+// StartSymbols returns the start productions declared in the grammar,
+// in the order they are declared. They are declared with directives like
+//
+//	; ebnf:start chunk stat exp
+//
+// anywhere in the grammar.
+func StartSymbols(grammar Grammar) []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, d := range Directives(grammar) {
+		if d.Key != "ebnf:start" {
+			continue
+		}
+		for _, name := range d.Args {
+			if !seen[name] {
+				seen[name], list = true, append(list, name)
+			}
+		}
+	}
+	return list
+}
+
+// Reachability returns the names of the productions reachable from each
+// start production, including the start production itself, in source
+// order. A terminal defined by a lexical production reaches that
+// production. Start productions that are not defined reach nothing.
+// See Graph.Reachable.
+func Reachability(grammar Grammar, start ...string) map[string][]string {
+	g := NewGraph(grammar)
+	reach := make(map[string][]string)
+	for _, root := range start {
+		reach[root] = g.Reachable(root)
+	}
+	return reach
+}
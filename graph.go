@@ -0,0 +1,279 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"github.com/mdhender/ebnf/tokens"
+)
+
+// A Reference is a use of a production name in the expression of a production.
+// A terminal is a reference only if a lexical production defines it.
+type Reference struct {
+	From string          // the production that contains the reference
+	To   string          // the name referenced; a nonterminal may not be defined
+	Pos  tokens.Position // position of the name
+}
+
+// A Graph is the dependency graph of the productions of a grammar: which
+// productions reference which, and where.
+//
+// The strongly connected components of the graph are the groups of
+// productions that can reach each other. A component with more than one
+// production, or with a production that references itself, is recursive.
+type Graph struct {
+	prods     []*Production // in source order
+	index     map[string]int
+	refs      [][]Reference // by production, in source order
+	users     [][]Reference // references to each production, by production in source order
+	edges     [][]int       // defined productions referenced by each, without duplicates
+	self      []bool        // by production; true if it references itself
+	component []int         // by production
+	groups    [][]int       // productions by component, in reverse topological order
+}
+
+// NewGraph returns the dependency graph of the grammar.
+func NewGraph(grammar Grammar) *Graph {
+	g := &Graph{prods: Productions(grammar), index: make(map[string]int)}
+	for i, prod := range g.prods {
+		g.index[prod.Name.String()] = i
+	}
+	g.refs = make([][]Reference, len(g.prods))
+	g.users = make([][]Reference, len(g.prods))
+	g.edges = make([][]int, len(g.prods))
+	g.self = make([]bool, len(g.prods))
+	for i, prod := range g.prods {
+		from := prod.Name.String()
+		seen := make(map[int]bool)
+		Apply(prod.Expr, func(c *Cursor) bool {
+			var ref Reference
+			switch x := c.Node().(type) {
+			case *Name:
+				ref = Reference{From: from, To: x.String(), Pos: Position(x)}
+			case *Literal:
+				// a terminal defined by a lexical production references it
+				if _, ok := g.index[x.String()]; !ok {
+					return true
+				}
+				ref = Reference{From: from, To: x.String(), Pos: Position(x)}
+			default:
+				return true
+			}
+			g.refs[i] = append(g.refs[i], ref)
+			if j, ok := g.index[ref.To]; ok {
+				g.users[j] = append(g.users[j], ref)
+				if j == i {
+					g.self[i] = true
+				} else if !seen[j] {
+					seen[j], g.edges[i] = true, append(g.edges[i], j)
+				}
+			}
+			return true
+		}, nil)
+	}
+	g.tarjan()
+	return g
+}
+
+// tarjan finds the strongly connected components with Tarjan's algorithm.
+// Components are found in reverse topological order: a component is
+// found after every component it references.
+func (g *Graph) tarjan() {
+	g.component = make([]int, len(g.prods))
+	index := make([]int, len(g.prods)) // order of discovery, 0 if not yet visited
+	low := make([]int, len(g.prods))
+	onStack := make([]bool, len(g.prods))
+	var stack []int
+	count := 0
+	var visit func(v int)
+	visit = func(v int) {
+		count++
+		index[v], low[v] = count, count
+		stack, onStack[v] = append(stack, v), true
+		for _, w := range g.edges[v] {
+			if index[w] == 0 {
+				visit(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var group []int
+		for {
+			w := stack[len(stack)-1]
+			stack, onStack[w] = stack[:len(stack)-1], false
+			g.component[w], group = len(g.groups), append(group, w)
+			if w == v {
+				break
+			}
+		}
+		// keep the productions of a component in source order
+		for i := 1; i < len(group); i++ {
+			for j := i; j > 0 && group[j] < group[j-1]; j-- {
+				group[j], group[j-1] = group[j-1], group[j]
+			}
+		}
+		g.groups = append(g.groups, group)
+	}
+	for v := range g.prods {
+		if index[v] == 0 {
+			visit(v)
+		}
+	}
+}
+
+func (g *Graph) names(list []int) []string {
+	names := []string{}
+	for _, i := range list {
+		names = append(names, g.prods[i].Name.String())
+	}
+	return names
+}
+
+// Productions returns the names of the productions in source order.
+func (g *Graph) Productions() []string {
+	list := make([]int, len(g.prods))
+	for i := range list {
+		list[i] = i
+	}
+	return g.names(list)
+}
+
+// References returns the references in the production, in source order,
+// including references to undefined names. It returns nil if the
+// production is not defined.
+// The slice is shared and must not be modified.
+func (g *Graph) References(name string) []Reference {
+	if i, ok := g.index[name]; ok {
+		return g.refs[i]
+	}
+	return nil
+}
+
+// ReferencedBy returns the references to the production from every
+// production, including itself, in the source order of the productions.
+// The slice is shared and must not be modified.
+func (g *Graph) ReferencedBy(name string) []Reference {
+	if i, ok := g.index[name]; ok {
+		return g.users[i]
+	}
+	return nil
+}
+
+// Uses returns the names of the other defined productions that the
+// production references, in the order of their first reference.
+func (g *Graph) Uses(name string) []string {
+	if i, ok := g.index[name]; ok {
+		return g.names(g.edges[i])
+	}
+	return nil
+}
+
+// Components returns the strongly connected components, each in source
+// order. A component comes after every component it references, so the
+// list is in reverse topological order.
+func (g *Graph) Components() [][]string {
+	var list [][]string
+	for _, group := range g.groups {
+		list = append(list, g.names(group))
+	}
+	return list
+}
+
+// Component returns the index in Components of the component that holds
+// the production, or -1 if it is not defined.
+func (g *Graph) Component(name string) int {
+	if i, ok := g.index[name]; ok {
+		return g.component[i]
+	}
+	return -1
+}
+
+// Recursive returns true if the production can reach itself.
+func (g *Graph) Recursive(name string) bool {
+	i, ok := g.index[name]
+	return ok && (g.self[i] || len(g.groups[g.component[i]]) > 1)
+}
+
+// RecursiveGroups returns the components of the productions that can
+// reach themselves, in the order of Components.
+// A group of one is a production that references itself.
+func (g *Graph) RecursiveGroups() [][]string {
+	var list [][]string
+	for _, group := range g.groups {
+		if len(group) > 1 || g.self[group[0]] {
+			list = append(list, g.names(group))
+		}
+	}
+	return list
+}
+
+// TopologicalOrder returns the productions that are not recursive, each
+// after every production it references that is not recursive.
+// Productions that are not ordered by a reference stay in source order.
+func (g *Graph) TopologicalOrder() []string {
+	acyclic := make([]bool, len(g.prods))
+	for _, group := range g.groups {
+		if len(group) == 1 && !g.self[group[0]] {
+			acyclic[group[0]] = true
+		}
+	}
+	// repeatedly take the first production in source order whose
+	// dependencies have all been taken
+	done := make([]bool, len(g.prods))
+	var list []int
+	for progress := true; progress; {
+		progress = false
+		for v := range g.prods {
+			if !acyclic[v] || done[v] {
+				continue
+			}
+			ready := true
+			for _, w := range g.edges[v] {
+				if acyclic[w] && !done[w] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[v], list, progress = true, append(list, v), true
+				break
+			}
+		}
+	}
+	return g.names(list)
+}
+
+// Reachable returns the productions that can be reached from the start
+// productions, including the start productions, in source order.
+func (g *Graph) Reachable(start ...string) []string {
+	reached := make([]bool, len(g.prods))
+	var worklist []int
+	for _, name := range start {
+		if i, ok := g.index[name]; ok && !reached[i] {
+			reached[i], worklist = true, append(worklist, i)
+		}
+	}
+	for len(worklist) != 0 {
+		v := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		for _, w := range g.edges[v] {
+			if !reached[w] {
+				reached[w], worklist = true, append(worklist, w)
+			}
+		}
+	}
+	var list []int
+	for i, ok := range reached {
+		if ok {
+			list = append(list, i)
+		}
+	}
+	return g.names(list)
+}
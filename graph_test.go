@@ -0,0 +1,115 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	grammar, errs := Parse([]byte(`
+		s = a b | c .
+		a = A [ b a ] .
+		b = B { c } x .
+		c = C | d .
+		d = c D .
+		e = e E .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	g := NewGraph(grammar)
+
+	var refs []string
+	for _, ref := range g.References("a") {
+		refs = append(refs, fmt.Sprintf("%s->%s %d:%d", ref.From, ref.To, ref.Pos.Line, ref.Pos.Col))
+	}
+	for _, ref := range g.ReferencedBy("c") {
+		refs = append(refs, fmt.Sprintf("%s->%s %d:%d", ref.From, ref.To, ref.Pos.Line, ref.Pos.Col))
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want string
+	}{
+		{"productions", strings.Join(g.Productions(), " "), "s a b c d e"},
+		{"references", strings.Join(refs, ", "), "a->b 3:11, a->a 3:13, s->c 2:13, b->c 4:11, d->c 6:7"},
+		{"uses", strings.Join(g.Uses("b"), " "), "c"},
+		{"components", fmt.Sprint(g.Components()), "[[c d] [b] [a] [s] [e]]"},
+		{"recursive groups", fmt.Sprint(g.RecursiveGroups()), "[[c d] [a] [e]]"},
+		{"topological order", strings.Join(g.TopologicalOrder(), " "), "b s"},
+		{"reachable", strings.Join(g.Reachable("b"), " "), "b c d"},
+		{"reachable", strings.Join(g.Reachable("e", "d"), " "), "c d e"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, tc.got)
+		}
+	}
+	for name, want := range map[string]bool{"s": false, "a": true, "b": false, "d": true, "e": true, "x": false} {
+		if got := g.Recursive(name); got != want {
+			t.Errorf("Recursive(%q): want %v, got %v", name, want, got)
+		}
+	}
+	if g.Component("c") != g.Component("d") || g.Component("x") != -1 {
+		t.Errorf("want c and d in one component and x in none")
+	}
+}
+
+func TestGraphLexical(t *testing.T) {
+	grammar, errs := Parse([]byte(`program = Number Comma .
+		Number = Digit { Digit } .`))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	g := NewGraph(grammar)
+	var refs []string
+	for _, ref := range g.ReferencedBy("Number") {
+		refs = append(refs, fmt.Sprintf("%s->%s %d:%d", ref.From, ref.To, ref.Pos.Line, ref.Pos.Col))
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want string
+	}{
+		// Comma and Digit come from the scanner and are not references
+		{"references", strings.Join(refs, ", "), "program->Number 1:11"},
+		{"uses", strings.Join(g.Uses("program"), " "), "Number"},
+		{"reachable", strings.Join(g.Reachable("program"), " "), "program Number"},
+		{"topological order", strings.Join(g.TopologicalOrder(), " "), "Number program"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, tc.got)
+		}
+	}
+}
+
+func TestGraphLua(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar, errs := Parse(input)
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	g := NewGraph(grammar)
+	groups := g.RecursiveGroups()
+	if len(groups) != 1 || len(groups[0]) != 15 {
+		t.Errorf("want one recursive group of 15 productions, got %v", groups)
+	}
+	if got := len(g.Reachable("chunk")); got != len(grammar) {
+		t.Errorf("want every production reachable from chunk, got %d", got)
+	}
+	// every production comes after the ones it references
+	seen := make(map[string]bool)
+	for _, name := range g.TopologicalOrder() {
+		for _, to := range g.Uses(name) {
+			if !g.Recursive(to) && !seen[to] {
+				t.Errorf("%s comes before %s", name, to)
+			}
+		}
+		seen[name] = true
+	}
+}
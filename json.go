@@ -0,0 +1,341 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mdhender/ebnf/tokens"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONVersion is the version of the schema written by EncodeJSON.
+const JSONVersion = 1
+
+// EncodeJSON writes the grammar to w as JSON.
+//
+// Version 1 of the schema is
+//
+//	grammar    = { "version": 1, "productions": [ production, ... ] }
+//	production = { "doc": [ comment, ... ],   // optional
+//	               "name": name,
+//	               "expr": node,              // optional, missing for an empty production
+//	               "lineComment": comment,    // optional
+//	               "trailer": [ comment, ... ], // optional
+//	               "end": pos }               // optional, position of the terminator
+//	node       = { "kind": kind, "pos": pos, ... }
+//	pos        = { "line": int, "col": int }
+//
+// where kind and the remaining fields of a node are
+//
+//	"alternative", "sequence"        "list": [ node, ... ] with at least one node
+//	"name", "literal", "comment"     "text": string
+//	"group", "option", "repetition"  "body": node, "end": pos of the closing bracket
+//	"bad"                            "text": string, "token": token kind, "error": string
+//
+// The "pos" of an alternative or sequence is omitted since it is the position
+// of its first node. Any other "pos" or "end" is omitted when the node has
+// no token. Productions are written in source order.
+// The trivia kept by ParseCST is not written.
+func EncodeJSON(w io.Writer, grammar Grammar) error {
+	jg := jsonGrammar{Version: JSONVersion, Productions: []*jsonProduction{}}
+	for _, prod := range Productions(grammar) {
+		jp := &jsonProduction{
+			Name:        jsonToken("name", prod.Name.tok),
+			LineComment: jsonComment(prod.LineComment),
+			End:         jsonPosition(prod.end),
+		}
+		for _, c := range prod.Doc {
+			jp.Doc = append(jp.Doc, jsonComment(c))
+		}
+		for _, c := range prod.Trailer {
+			jp.Trailer = append(jp.Trailer, jsonComment(c))
+		}
+		if prod.Expr != nil {
+			jp.Expr = jsonExpression(prod.Expr)
+		}
+		jg.Productions = append(jg.Productions, jp)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jg)
+}
+
+// DecodeJSON reads a grammar written by EncodeJSON.
+// It returns an error if the schema version is not supported,
+// if a node is not valid, or if a production is defined more than once.
+func DecodeJSON(r io.Reader) (Grammar, error) {
+	var jg jsonGrammar
+	if err := json.NewDecoder(r).Decode(&jg); err != nil {
+		return nil, err
+	} else if jg.Version != JSONVersion {
+		return nil, fmt.Errorf("json: unsupported version %d", jg.Version)
+	}
+	grammar := make(Grammar)
+	var errs errorList
+	for i, jp := range jg.Productions {
+		if jp == nil || jp.Name == nil {
+			errs = append(errs, fmt.Errorf("json: production %d: missing name", i))
+			continue
+		}
+		prod := &Production{
+			Name: &Name{tok: jp.Name.token(nameKind(jp.Name.Text))},
+			end:  jp.End.token(tokens.TERMINATOR),
+		}
+		var err error
+		if prod.Doc, err = decodeComments(jp.Doc); err != nil {
+			errs = append(errs, err)
+		}
+		if prod.Trailer, err = decodeComments(jp.Trailer); err != nil {
+			errs = append(errs, err)
+		}
+		if jp.LineComment != nil {
+			if prod.LineComment, err = jp.LineComment.comment(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if jp.Expr != nil {
+			if prod.Expr, err = jp.Expr.expression(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err = grammar.define(prod); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return grammar, nil
+}
+
+// MarshalJSON implements json.Marshaler using EncodeJSON.
+func (g Grammar) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeJSON(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler using DecodeJSON.
+func (g *Grammar) UnmarshalJSON(data []byte) error {
+	grammar, err := DecodeJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*g = grammar
+	return nil
+}
+
+type jsonGrammar struct {
+	Version     int               `json:"version"`
+	Productions []*jsonProduction `json:"productions"`
+}
+
+type jsonProduction struct {
+	Doc         []*jsonNode `json:"doc,omitempty"`
+	Name        *jsonNode   `json:"name"`
+	Expr        *jsonNode   `json:"expr,omitempty"`
+	LineComment *jsonNode   `json:"lineComment,omitempty"`
+	Trailer     []*jsonNode `json:"trailer,omitempty"`
+	End         *jsonPos    `json:"end,omitempty"`
+}
+
+type jsonNode struct {
+	Kind  string      `json:"kind"`
+	Pos   *jsonPos    `json:"pos,omitempty"`
+	Text  string      `json:"text,omitempty"`
+	List  []*jsonNode `json:"list,omitempty"`
+	Body  *jsonNode   `json:"body,omitempty"`
+	End   *jsonPos    `json:"end,omitempty"`
+	Token string      `json:"token,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+type jsonPos struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+func jsonPosition(tok *tokens.Token) *jsonPos {
+	if tok == nil {
+		return nil
+	}
+	return &jsonPos{Line: tok.Pos.Line, Col: tok.Pos.Col}
+}
+
+func jsonToken(kind string, tok *tokens.Token) *jsonNode {
+	n := &jsonNode{Kind: kind, Pos: jsonPosition(tok)}
+	if tok != nil {
+		n.Text = string(tok.Text)
+	}
+	return n
+}
+
+func jsonComment(c *Comment) *jsonNode {
+	if c == nil {
+		return nil
+	}
+	return jsonToken("comment", c.tok)
+}
+
+func jsonExpression(x Expression) *jsonNode {
+	switch x := x.(type) {
+	case Alternative:
+		n := &jsonNode{Kind: "alternative"}
+		for _, e := range x {
+			n.List = append(n.List, jsonExpression(e))
+		}
+		return n
+	case Sequence:
+		n := &jsonNode{Kind: "sequence"}
+		for _, e := range x {
+			n.List = append(n.List, jsonExpression(e))
+		}
+		return n
+	case *Name:
+		return jsonToken("name", x.tok)
+	case *Literal:
+		return jsonToken("literal", x.tok)
+	case *Group:
+		return &jsonNode{Kind: "group", Pos: jsonPosition(x.tok), Body: jsonExpression(x.Body), End: jsonPosition(x.end)}
+	case *Option:
+		return &jsonNode{Kind: "option", Pos: jsonPosition(x.tok), Body: jsonExpression(x.Body), End: jsonPosition(x.end)}
+	case *Repetition:
+		return &jsonNode{Kind: "repetition", Pos: jsonPosition(x.tok), Body: jsonExpression(x.Body), End: jsonPosition(x.end)}
+	case *Bad:
+		n := jsonToken("bad", x.tok)
+		if x.tok != nil {
+			n.Token = x.tok.Kind.String()
+		}
+		if x.err != nil {
+			n.Error = x.err.Error()
+		}
+		return n
+	}
+	panic(fmt.Sprintf("internal error: unexpected type %T", x))
+}
+
+// token returns a token of the given kind at the position, or nil if there is no position.
+func (p *jsonPos) token(kind tokens.Kind) *tokens.Token {
+	if p == nil {
+		return nil
+	}
+	return &tokens.Token{Pos: tokens.Position{Line: p.Line, Col: p.Col}, Kind: kind}
+}
+
+// token returns a token of the given kind with the position and text of the node.
+// Unlike the token for a missing position, it is never nil since names,
+// literals, and comments always have a token.
+func (n *jsonNode) token(kind tokens.Kind) *tokens.Token {
+	tok := &tokens.Token{Kind: kind}
+	if n.Pos != nil {
+		tok.Pos = tokens.Position{Line: n.Pos.Line, Col: n.Pos.Col}
+	}
+	if n.Text != "" {
+		tok.Text = []byte(n.Text)
+	}
+	return tok
+}
+
+// nameKind returns the kind of token for the name of a production:
+// TERMINAL for a lexical production, which starts with an upper case
+// letter, and NONTERMINAL for any other.
+func nameKind(name string) tokens.Kind {
+	if r, _ := utf8.DecodeRuneInString(name); unicode.IsUpper(r) {
+		return tokens.TERMINAL
+	}
+	return tokens.NONTERMINAL
+}
+
+func (n *jsonNode) error(format string, args ...any) error {
+	line := 0
+	if n.Pos != nil {
+		line = n.Pos.Line
+	}
+	return fmt.Errorf("json: %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (n *jsonNode) comment() (*Comment, error) {
+	if n.Kind != "comment" {
+		return nil, n.error("expected comment, found %q", n.Kind)
+	}
+	return &Comment{tok: n.token(tokens.COMMENT)}, nil
+}
+
+func decodeComments(list []*jsonNode) (comments []*Comment, err error) {
+	for _, n := range list {
+		if n == nil {
+			return nil, fmt.Errorf("json: missing comment")
+		}
+		c, err := n.comment()
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+func (n *jsonNode) expression() (Expression, error) {
+	if n == nil {
+		return nil, fmt.Errorf("json: missing node")
+	}
+	switch n.Kind {
+	case "alternative", "sequence":
+		if len(n.List) == 0 {
+			return nil, n.error("empty %s", n.Kind)
+		}
+		list := make([]Expression, 0, len(n.List))
+		for _, e := range n.List {
+			x, err := e.expression()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, x)
+		}
+		if n.Kind == "alternative" {
+			return Alternative(list), nil
+		}
+		return Sequence(list), nil
+	case "name":
+		return &Name{tok: n.token(tokens.NONTERMINAL)}, nil
+	case "literal":
+		return &Literal{tok: n.token(tokens.TERMINAL)}, nil
+	case "group", "option", "repetition":
+		if n.Pos == nil {
+			return nil, n.error("missing pos")
+		}
+		body, err := n.Body.expression()
+		if err != nil {
+			return nil, err
+		}
+		switch n.Kind {
+		case "group":
+			return &Group{tok: n.Pos.token(tokens.START_GROUP), Body: body, end: n.End.token(tokens.END_GROUP)}, nil
+		case "option":
+			return &Option{tok: n.Pos.token(tokens.START_OPTION), Body: body, end: n.End.token(tokens.END_OPTION)}, nil
+		}
+		return &Repetition{tok: n.Pos.token(tokens.START_REPETITION), Body: body, end: n.End.token(tokens.END_REPETITION)}, nil
+	case "bad":
+		kind := tokens.UNKNOWN
+		for k := tokens.UNKNOWN; k <= tokens.EOF; k++ {
+			if k.String() == n.Token {
+				kind = k
+			}
+		}
+		tok := n.Pos.token(kind)
+		if tok != nil && n.Text != "" {
+			tok.Text = []byte(n.Text)
+		}
+		return &Bad{tok: tok, err: errors.New(n.Error)}, nil
+	}
+	return nil, n.error("unknown node kind %q", n.Kind)
+}
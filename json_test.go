@@ -0,0 +1,86 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder()
+	b.Prod("a").Alt(b.Seq(b.T("A"), b.Opt(b.N("a"))), b.Rep(b.Group(b.Alt(b.T("B"), b.T("C")))))
+	b.Prod("b").Expr(nil)
+	built, _ := b.Grammar()
+
+	for _, src := range append(goodGrammars, string(input), "; doc\na = B ; line\n| (C) .\n; trailer") {
+		grammar, errs := Parse([]byte(src))
+		if errs != nil {
+			t.Fatalf("Parse(%q) failed: %v", src, errs)
+		}
+		checkJSON(t, src, grammar)
+	}
+	checkJSON(t, "builder", built)
+
+	// json.Marshal and json.Unmarshal use the same encoding
+	data, err := json.Marshal(built)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Grammar
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	} else if !reflect.DeepEqual(built, decoded) {
+		t.Errorf("Unmarshal(Marshal(grammar)) differs from grammar")
+	}
+}
+
+func checkJSON(t *testing.T, src string, grammar Grammar) {
+	var buf bytes.Buffer
+	if err := EncodeJSON(&buf, grammar); err != nil {
+		t.Errorf("EncodeJSON(%q) failed: %v", src, err)
+		return
+	}
+	decoded, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Errorf("DecodeJSON(%q) failed: %v", src, err)
+	} else if !reflect.DeepEqual(grammar, decoded) {
+		t.Errorf("DecodeJSON(EncodeJSON(%q)) differs from grammar", src)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"version": 2, "productions": []}`,
+		`{"version": 1, "productions": [{"expr": {"kind": "name", "text": "a"}}]}`,
+		`{"version": 1, "productions": [{"name": {"kind": "name", "text": "a"}, "expr": {"kind": "sequence"}}]}`,
+		`{"version": 1, "productions": [{"name": {"kind": "name", "text": "a"}, "expr": {"kind": "option"}}]}`,
+		`{"version": 1, "productions": [{"name": {"kind": "name", "text": "a"}, "expr": {"kind": "range"}}]}`,
+		`{"version": 1, "productions": [{"name": {"kind": "name", "text": "a"}}, {"name": {"kind": "name", "text": "a"}}]}`,
+	} {
+		if _, err := DecodeJSON(strings.NewReader(src)); err == nil {
+			t.Errorf("DecodeJSON(%s) should have failed", src)
+		}
+	}
+}
+
+func TestDecodeJSONMissingPos(t *testing.T) {
+	for _, kind := range []string{"group", "option", "repetition"} {
+		src := `{"version": 1, "productions": [{"name": {"kind": "name", "text": "a", "pos": {"line": 1, "col": 1}},
+			"expr": {"kind": "` + kind + `", "body": {"kind": "literal", "text": "A", "pos": {"line": 1, "col": 7}}}}]}`
+		_, err := DecodeJSON(strings.NewReader(src))
+		if want := "json: 0: missing pos"; err == nil || err.Error() != want {
+			t.Errorf("%s: want %q, got %v", kind, want, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lint
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/tokens"
+	"sort"
+	"strconv"
+)
+
+// A Severity is how serious a problem is.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	panic(fmt.Sprintf("assert(severity != %d)", s))
+}
+
+// ParseSeverity returns the severity with the name.
+func ParseSeverity(name string) (Severity, error) {
+	for s := Info; s <= Error; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("lint: unknown severity %q", name)
+}
+
+// A Problem is something found by a lint rule.
+type Problem struct {
+	Pos        tokens.Position
+	Production string // the production it was found in, "" for the whole grammar
+	Rule       string // name of the rule that found it
+	Severity   Severity
+	Message    string
+}
+
+// Error returns the problem as "line:col: production: message [severity rule]".
+// The production is left out for problems with the whole grammar.
+func (p Problem) Error() string {
+	if p.Production == "" {
+		return fmt.Sprintf("%d:%d: %s [%s %s]", p.Pos.Line, p.Pos.Col, p.Message, p.Severity, p.Rule)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s [%s %s]", p.Pos.Line, p.Pos.Col, p.Production, p.Message, p.Severity, p.Rule)
+}
+
+// A Rule is a named check run by a Linter.
+type Rule struct {
+	Name     string
+	Doc      string   // one line description
+	Severity Severity // severity of the problems it reports, unless configured
+	Default  bool     // part of the default rule set
+	// Options are the options the rule accepts, with their default values.
+	Options map[string]string
+	Check   func(pass *Pass)
+}
+
+var registry = make(map[string]*Rule)
+
+// Register adds a rule to the registry so that a Linter can enable it.
+// It returns an error if the rule has no name or check, or if a rule
+// with the same name is already registered.
+func Register(rule *Rule) error {
+	if rule.Name == "" || rule.Check == nil {
+		return fmt.Errorf("lint: rule %q must have a name and a check", rule.Name)
+	} else if _, ok := registry[rule.Name]; ok {
+		return fmt.Errorf("lint: rule %q is already registered", rule.Name)
+	}
+	registry[rule.Name] = rule
+	return nil
+}
+
+// unregister removes the rule with the name from the registry.
+func unregister(name string) { delete(registry, name) }
+
+// Lookup returns the registered rule with the name, or nil.
+func Lookup(name string) *Rule { return registry[name] }
+
+// Rules returns the registered rules sorted by name.
+func Rules() []*Rule {
+	var list []*Rule
+	for _, rule := range registry {
+		list = append(list, rule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// A Pass is a run of one rule over a grammar.
+type Pass struct {
+	Grammar  ebnf.Grammar
+	rule     *Rule
+	severity Severity
+	options  map[string]string
+	problems []Problem
+	err      error
+}
+
+// Report records a problem in the production.
+func (p *Pass) Report(prod string, pos tokens.Position, format string, args ...any) {
+	p.problems = append(p.problems, Problem{
+		Pos:        pos,
+		Production: prod,
+		Rule:       p.rule.Name,
+		Severity:   p.severity,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// Errorf makes the run fail with the error, for example because an
+// option has a bad value. Only the first error of a pass is kept.
+func (p *Pass) Errorf(format string, args ...any) {
+	if p.err == nil {
+		p.err = fmt.Errorf("lint: %s: %s", p.rule.Name, fmt.Sprintf(format, args...))
+	}
+}
+
+// Option returns the value of the option.
+func (p *Pass) Option(name string) string { return p.options[name] }
+
+// Int returns the value of the option as an integer.
+// If the value is not an integer, the run fails with an error.
+func (p *Pass) Int(name string) int {
+	n, err := strconv.Atoi(p.options[name])
+	if err != nil {
+		p.Errorf("option %s: %q is not an integer", name, p.options[name])
+	}
+	return n
+}
+
+// Productions returns the productions in source order.
+func (p *Pass) Productions() []*ebnf.Production { return ebnf.Productions(p.Grammar) }
+
+// A Linter runs a set of enabled rules over grammars.
+type Linter struct {
+	enabled []*enabled
+}
+
+type enabled struct {
+	rule     *Rule
+	severity Severity
+	options  map[string]string
+}
+
+// New returns a Linter with no rules enabled.
+func New() *Linter { return &Linter{} }
+
+// Default returns a Linter with the default rule set enabled.
+func Default() *Linter {
+	l := New()
+	for _, rule := range Rules() {
+		if rule.Default {
+			_ = l.Enable(rule.Name, nil)
+		}
+	}
+	return l
+}
+
+// Enable enables the rule with its default severity and the options,
+// which override the defaults of the rule. Enabling a rule again
+// replaces its options. It returns an error if the rule is not
+// registered or does not accept one of the options.
+func (l *Linter) Enable(name string, options map[string]string) error {
+	rule := registry[name]
+	if rule == nil {
+		return fmt.Errorf("lint: unknown rule %q", name)
+	}
+	e := &enabled{rule: rule, severity: rule.Severity, options: make(map[string]string)}
+	for k, v := range rule.Options {
+		e.options[k] = v
+	}
+	for k, v := range options {
+		if _, ok := rule.Options[k]; !ok {
+			return fmt.Errorf("lint: %s: unknown option %q", name, k)
+		}
+		e.options[k] = v
+	}
+	l.Disable(name)
+	l.enabled = append(l.enabled, e)
+	return nil
+}
+
+// Disable disables the rule.
+func (l *Linter) Disable(name string) {
+	for i, e := range l.enabled {
+		if e.rule.Name == name {
+			l.enabled = append(l.enabled[:i], l.enabled[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetSeverity changes the severity of an enabled rule.
+func (l *Linter) SetSeverity(name string, severity Severity) error {
+	for _, e := range l.enabled {
+		if e.rule.Name == name {
+			e.severity = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("lint: rule %q is not enabled", name)
+}
+
+// Enabled returns the names of the enabled rules in the order they run.
+func (l *Linter) Enabled() []string {
+	var names []string
+	for _, e := range l.enabled {
+		names = append(names, e.rule.Name)
+	}
+	return names
+}
+
+// Run runs the enabled rules over the grammar and returns the problems
+// they report, sorted by position and then by rule name.
+//
+// Problems can be suppressed with comments. A comment
+//
+//	; lint:ignore rule ...
+//
+// attached to a production, before it or in its expression, suppresses
+// the rules in that production, and a comment
+//
+//	; lint:file-ignore rule ...
+//
+// anywhere in the grammar suppresses them everywhere.
+func (l *Linter) Run(grammar ebnf.Grammar) ([]Problem, error) {
+	fileIgnore, prodIgnore := suppressions(grammar)
+	var list []Problem
+	for _, e := range l.enabled {
+		pass := &Pass{Grammar: grammar, rule: e.rule, severity: e.severity, options: e.options}
+		e.rule.Check(pass)
+		if pass.err != nil {
+			return nil, pass.err
+		}
+		for _, p := range pass.problems {
+			if !fileIgnore[p.Rule] && !prodIgnore[p.Production][p.Rule] {
+				list = append(list, p)
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Pos, list[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		} else if a.Col != b.Col {
+			return a.Col < b.Col
+		}
+		return list[i].Rule < list[j].Rule
+	})
+	return list, nil
+}
+
+// suppressions returns the rules ignored in the whole grammar and the
+// rules ignored in each production.
+func suppressions(grammar ebnf.Grammar) (map[string]bool, map[string]map[string]bool) {
+	file, prods := make(map[string]bool), make(map[string]map[string]bool)
+	for _, d := range ebnf.Directives(grammar) {
+		switch d.Key {
+		case "lint:file-ignore":
+			for _, rule := range d.Args {
+				file[rule] = true
+			}
+		case "lint:ignore":
+			if d.Production == "" {
+				// after the last production; it applies to no production
+				continue
+			}
+			if prods[d.Production] == nil {
+				prods[d.Production] = make(map[string]bool)
+			}
+			for _, rule := range d.Args {
+				prods[d.Production][rule] = true
+			}
+		}
+	}
+	return file, prods
+}
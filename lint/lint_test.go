@@ -0,0 +1,199 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lint
+
+import (
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, l *Linter, src string) string {
+	t.Helper()
+	problems, err := l.Run(parse(t, src))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var list []string
+	for _, p := range problems {
+		list = append(list, p.Error())
+	}
+	return strings.Join(list, "\n")
+}
+
+func TestDefault(t *testing.T) {
+	input := `program = Stmt_list | camelCase .
+camelCase = A | A .
+empty = .
+Stmt_list = A B .`
+	want := strings.Join([]string{
+		`1:11: program: terminal "Stmt_list" does not match ^[A-Z][A-Za-z0-9]*$ [warning terminal-case]`,
+		`1:23: program: nonterminal "camelCase" does not match ^[a-z][a-z0-9]*(_[a-z0-9]+)*$ [warning nonterminal-case]`,
+		`2:17: camelCase: alternatives 1 and 2 are the same [warning redundant]`,
+		`3:1: empty: production is empty [warning no-empty-production]`,
+	}, "\n")
+	if got := run(t, Default(), input); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestDefaultLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Default()
+	want := `82:1: binop: 21 alternatives is more than 20 [warning max-alternatives]`
+	if got := run(t, l, string(input)); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	input := `s = A | B | C .`
+	l := New()
+	if err := l.Enable("max-alternatives", map[string]string{"max": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `1:1: s: 3 alternatives is more than 2 [warning max-alternatives]`
+	if got := run(t, l, input); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if err := l.SetSeverity("max-alternatives", Error); err != nil {
+		t.Fatal(err)
+	}
+	want = `1:1: s: 3 alternatives is more than 2 [error max-alternatives]`
+	if got := run(t, l, input); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if err := l.SetSeverity("redundant", Error); err == nil {
+		t.Errorf("SetSeverity of a rule that is not enabled: want error")
+	}
+	if err := l.Enable("max-alternatives", map[string]string{"min": "2"}); err == nil {
+		t.Errorf("Enable with an unknown option: want error")
+	}
+	if err := l.Enable("no-such-rule", nil); err == nil {
+		t.Errorf("Enable of an unknown rule: want error")
+	}
+	if err := l.Enable("max-alternatives", map[string]string{"max": "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Run(parse(t, input)); err == nil {
+		t.Errorf("Run with a bad integer option: want error")
+	}
+	l.Disable("max-alternatives")
+	if got := l.Enabled(); len(got) != 0 {
+		t.Errorf("Disable: want no rules enabled, got %v", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	rule := &Rule{
+		Name:     "test-single-letter",
+		Doc:      "productions have names longer than one letter",
+		Severity: Info,
+		Check: func(pass *Pass) {
+			for _, prod := range pass.Productions() {
+				if name := prod.Name.String(); len(name) == 1 {
+					pass.Report(name, ebnf.Position(prod), "name is a single letter")
+				}
+			}
+		},
+	}
+	if err := Register(rule); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregister(rule.Name) })
+	if err := Register(rule); err == nil {
+		t.Errorf("Register twice: want error")
+	}
+	if err := Register(&Rule{Name: "test-no-check"}); err == nil {
+		t.Errorf("Register without a check: want error")
+	}
+	if Lookup("test-single-letter") != rule {
+		t.Errorf("Lookup: want registered rule")
+	}
+	for _, name := range Default().Enabled() {
+		if name == rule.Name {
+			t.Errorf("Default: want rule %q disabled", name)
+		}
+	}
+	l := New()
+	if err := l.Enable(rule.Name, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `2:1: b: name is a single letter [info test-single-letter]`
+	if got := run(t, l, "stmt = b .\nb = B ."); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestPassErrorf(t *testing.T) {
+	rule := &Rule{
+		Name:     "test-max-name",
+		Doc:      "production names are not longer than max letters",
+		Severity: Info,
+		Options:  map[string]string{"max": "8"},
+		Check: func(pass *Pass) {
+			if max := pass.Int("max"); max < 1 {
+				pass.Errorf("option max: %d is less than 1", max)
+				pass.Errorf("not kept")
+			}
+		},
+	}
+	if err := Register(rule); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregister(rule.Name) })
+	l := New()
+	if err := l.Enable(rule.Name, map[string]string{"max": "0"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := l.Run(parse(t, "s = A ."))
+	if want := "lint: test-max-name: option max: 0 is less than 1"; err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	for i, tc := range []struct {
+		input string
+		want  string
+	}{
+		{"s = A | A .\nt = B | B .", "1:9: s: alternatives 1 and 2 are the same [warning redundant]\n2:9: t: alternatives 1 and 2 are the same [warning redundant]"},
+		{"; lint:ignore redundant\ns = A | A .\nt = B | B .", "3:9: t: alternatives 1 and 2 are the same [warning redundant]"},
+		{"s = A | A . ; lint:ignore redundant\nt = B | B .", "2:9: t: alternatives 1 and 2 are the same [warning redundant]"},
+		{"s = A\n; lint:ignore other redundant\n| A .\nt = B | B .", "4:9: t: alternatives 1 and 2 are the same [warning redundant]"},
+		{"s = A | A .\n; lint:ignore max-alternatives\nt = B | B .", "1:9: s: alternatives 1 and 2 are the same [warning redundant]\n3:9: t: alternatives 1 and 2 are the same [warning redundant]"},
+		{"; lint:file-ignore redundant\ns = A | A .\nt = B | B .", ""},
+		{"s = A | A .\nt = B | B .\n; lint:file-ignore redundant", ""},
+		// a lint:ignore after the last production applies to no production
+		{"s = A | A .\nt = B | B .\n; lint:ignore redundant", "1:9: s: alternatives 1 and 2 are the same [warning redundant]\n2:9: t: alternatives 1 and 2 are the same [warning redundant]"},
+	} {
+		problems, err := Default().Run(parse(t, tc.input))
+		if err != nil {
+			t.Fatalf("%d: Run failed: %v", i+1, err)
+		}
+		var got []string
+		for _, p := range problems {
+			got = append(got, p.Error())
+		}
+		if strings.Join(got, "\n") != tc.want {
+			t.Errorf("%d: want\n%s\ngot\n%s", i+1, tc.want, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{Info, Warning, Error} {
+		if got, err := ParseSeverity(s.String()); err != nil || got != s {
+			t.Errorf("ParseSeverity(%q): want %v, got %v, %v", s, s, got, err)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Errorf("ParseSeverity(\"fatal\"): want error")
+	}
+}
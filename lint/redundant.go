@@ -0,0 +1,128 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+// Package lint reports constructs in EBNF grammars that are legal but
+// are likely mistakes, such as alternatives that were pasted twice.
+package lint
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/tokens"
+)
+
+// Redundant reports the alternatives that add nothing to the grammar.
+// Groups are looked through, so "a (b c)" is the same as "a b c".
+// It reports
+//
+//	alternatives that are the same, as in "a b | a b"
+//	alternatives that another one covers by adding an optional tail,
+//	  as in "a | a [b]" or "a | a {b}"
+//	options inside options or repetitions, as in "[[a]]" or "{[a]}"
+//
+// Problems are returned in source order of the productions.
+func Redundant(grammar ebnf.Grammar) []Problem {
+	var list []Problem
+	for _, prod := range ebnf.Productions(grammar) {
+		r := &redundant{prod: prod.Name.String()}
+		r.expr(prod.Expr)
+		list = append(list, r.problems...)
+	}
+	return list
+}
+
+type redundant struct {
+	prod     string
+	problems []Problem
+}
+
+func (r *redundant) report(pos tokens.Position, format string, args ...any) {
+	r.problems = append(r.problems, Problem{Pos: pos, Production: r.prod, Rule: "redundant", Severity: Warning, Message: fmt.Sprintf(format, args...)})
+}
+
+// expr checks the alternatives of the expression and then the
+// expressions nested in them.
+func (r *redundant) expr(x ebnf.Expression) {
+	alts := ebnf.Alternatives(x)
+	if len(alts) > 1 {
+		r.alternatives(alts)
+	}
+	for _, alt := range alts {
+		for _, e := range alt {
+			switch e := e.(type) {
+			case *ebnf.Group:
+				r.expr(e.Body)
+			case *ebnf.Option:
+				if inner, ok := sole(e.Body).(*ebnf.Option); ok {
+					r.report(ebnf.Position(inner), "option inside an option adds nothing")
+				}
+				r.expr(e.Body)
+			case *ebnf.Repetition:
+				if inner, ok := sole(e.Body).(*ebnf.Option); ok {
+					r.report(ebnf.Position(inner), "option inside a repetition adds nothing")
+				}
+				r.expr(e.Body)
+			}
+		}
+	}
+}
+
+// alternatives compares each pair of alternatives.
+func (r *redundant) alternatives(alts [][]ebnf.Expression) {
+	for j := 1; j < len(alts); j++ {
+		for i := 0; i < j; i++ {
+			a, b := alts[i], alts[j]
+			switch {
+			case len(a) == len(b) && same(a, b):
+				r.report(position(b), "alternatives %d and %d are the same", i+1, j+1)
+			case len(a) < len(b) && same(a, b[:len(a)]) && optional(b[len(a):]):
+				r.report(position(a), "alternative %d is redundant: alternative %d only adds an optional tail", i+1, j+1)
+			case len(b) < len(a) && same(b, a[:len(b)]) && optional(a[len(b):]):
+				r.report(position(b), "alternative %d is redundant: alternative %d only adds an optional tail", j+1, i+1)
+			default:
+				continue
+			}
+			break // one report for each alternative is enough
+		}
+	}
+}
+
+// same returns true if the elements are structurally equal.
+func same(a, b []ebnf.Expression) bool {
+	for i := range a {
+		if !ebnf.EqualExpr(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// optional returns true if every element is an option or a repetition.
+func optional(list []ebnf.Expression) bool {
+	for _, e := range list {
+		switch e.(type) {
+		case *ebnf.Option, *ebnf.Repetition:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// position returns the position of an alternative.
+func position(alt []ebnf.Expression) tokens.Position {
+	if len(alt) == 0 {
+		return tokens.Position{}
+	}
+	return ebnf.Position(alt[0])
+}
+
+// sole returns the only element of the expression, or nil if it does not
+// have exactly one.
+func sole(x ebnf.Expression) ebnf.Expression {
+	if alts := ebnf.Alternatives(x); len(alts) == 1 && len(alts[0]) == 1 {
+		return alts[0][0]
+	}
+	return nil
+}
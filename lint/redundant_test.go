@@ -0,0 +1,58 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lint
+
+import (
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src string) ebnf.Grammar {
+	t.Helper()
+	grammar, errs := ebnf.Parse([]byte(src))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	return grammar
+}
+
+func TestRedundant(t *testing.T) {
+	for i, tc := range []struct {
+		input string
+		want  []string
+	}{
+		{`s = A B | C | A B .`, []string{"1:15: s: alternatives 1 and 3 are the same [warning redundant]"}},
+		{`s = A (B C) | A B C .`, []string{"1:15: s: alternatives 1 and 2 are the same [warning redundant]"}},
+		{`s = A | (B | A) .`, []string{"1:14: s: alternatives 1 and 3 are the same [warning redundant]"}},
+		{`s = A | A [ B ] .`, []string{"1:5: s: alternative 1 is redundant: alternative 2 only adds an optional tail [warning redundant]"}},
+		{`s = A { B } [ C ] | A .`, []string{"1:21: s: alternative 2 is redundant: alternative 1 only adds an optional tail [warning redundant]"}},
+		{`s = [ [ A ] B ] | { [ C ] D } .`, nil},
+		{`s = [ ( [ A ] ) ] .`, []string{"1:9: s: option inside an option adds nothing [warning redundant]"}},
+		{`s = { [ A | B ] } .`, []string{"1:7: s: option inside a repetition adds nothing [warning redundant]"}},
+		// nested alternatives are checked on their own
+		{`s = A ( B | B ) | C .`, []string{"1:13: s: alternatives 1 and 2 are the same [warning redundant]"}},
+		{`s = A B | A C | A [ B C ] .`, nil},
+	} {
+		var got []string
+		for _, p := range Redundant(parse(t, tc.input)) {
+			got = append(got, p.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%d: want\n%s\ngot\n%s", i+1, strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestRedundantLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range Redundant(parse(t, string(input))) {
+		t.Errorf("want no problems, got %v", p)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lint
+
+import (
+	"github.com/mdhender/ebnf"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the default rule set
+func init() {
+	for _, rule := range []*Rule{
+		{
+			Name:     "nonterminal-case",
+			Doc:      "nonterminals are snake_case",
+			Severity: Warning,
+			Default:  true,
+			Options:  map[string]string{"pattern": `^[a-z][a-z0-9]*(_[a-z0-9]+)*$`},
+			Check:    func(pass *Pass) { checkNames(pass, false, "nonterminal") },
+		},
+		{
+			Name:     "terminal-case",
+			Doc:      "terminals are CamelCase",
+			Severity: Warning,
+			Default:  true,
+			Options:  map[string]string{"pattern": `^[A-Z][A-Za-z0-9]*$`},
+			Check:    func(pass *Pass) { checkNames(pass, true, "terminal") },
+		},
+		{
+			Name:     "max-alternatives",
+			Doc:      "productions have at most max alternatives",
+			Severity: Warning,
+			Default:  true,
+			Options:  map[string]string{"max": "20"},
+			Check:    checkMaxAlternatives,
+		},
+		{
+			Name:     "no-empty-production",
+			Doc:      `productions are not empty, as in "program = ."`,
+			Severity: Warning,
+			Default:  true,
+			Check:    checkEmpty,
+		},
+		{
+			Name:     "redundant",
+			Doc:      "alternatives and options add something",
+			Severity: Warning,
+			Default:  true,
+			Check: func(pass *Pass) {
+				for _, p := range Redundant(pass.Grammar) {
+					pass.Report(p.Production, p.Pos, "%s", p.Message)
+				}
+			},
+		},
+	} {
+		if err := Register(rule); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// checkNames reports the terminals or nonterminals that do not match
+// the pattern option, once each, where they are first defined or used.
+func checkNames(pass *Pass, terminals bool, kind string) {
+	pattern, err := regexp.Compile(pass.Option("pattern"))
+	if err != nil {
+		pass.Errorf("option pattern: %v", err)
+		return
+	}
+	seen := make(map[string]bool)
+	check := func(prod, name string, x ebnf.Expression) {
+		r, _ := utf8.DecodeRuneInString(name)
+		if seen[name] || unicode.IsUpper(r) != terminals {
+			return
+		}
+		seen[name] = true
+		if !pattern.MatchString(name) {
+			pass.Report(prod, ebnf.Position(x), "%s %q does not match %s", kind, name, pattern)
+		}
+	}
+	for _, prod := range pass.Productions() {
+		name := prod.Name.String()
+		check(name, name, prod)
+		ebnf.Apply(prod.Expr, func(c *ebnf.Cursor) bool {
+			switch x := c.Node().(type) {
+			case *ebnf.Name:
+				check(name, x.String(), x)
+			case *ebnf.Literal:
+				check(name, x.String(), x)
+			}
+			return true
+		}, nil)
+	}
+}
+
+func checkMaxAlternatives(pass *Pass) {
+	limit := pass.Int("max")
+	for _, prod := range pass.Productions() {
+		if x, ok := prod.Expr.(ebnf.Alternative); ok && len(x) > limit {
+			pass.Report(prod.Name.String(), ebnf.Position(prod), "%d alternatives is more than %d", len(x), limit)
+		}
+	}
+}
+
+func checkEmpty(pass *Pass) {
+	for _, prod := range pass.Productions() {
+		if prod.Expr == nil {
+			pass.Report(prod.Name.String(), ebnf.Position(prod), "production is empty")
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lr
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"sort"
+	"strings"
+)
+
+// A ConflictKind is the kind of an LR conflict.
+type ConflictKind int
+
+const (
+	ShiftReduce  ConflictKind = iota // a terminal can be shifted or a rule reduced
+	ReduceReduce                     // two rules can be reduced
+)
+
+func (k ConflictKind) String() string {
+	switch k {
+	case ShiftReduce:
+		return "shift/reduce"
+	case ReduceReduce:
+		return "reduce/reduce"
+	}
+	panic(fmt.Sprintf("assert(kind != %d)", k))
+}
+
+// A Conflict is a state where the automaton has more than one action for
+// some terminals. Terminals with the same actions are reported together.
+type Conflict struct {
+	a         *Automaton
+	State     int
+	Kind      ConflictKind
+	Terminals []string // "$" is the end of input
+	// Items are the indexes in the state of the items involved: the items
+	// that shift one of the terminals, then the items that are reduced.
+	Items []int
+}
+
+func (c Conflict) Error() string {
+	var items []string
+	for _, i := range c.Items {
+		items = append(items, c.a.ItemString(c.State, i))
+	}
+	line, prod := c.a.position(c.State, c.Items[len(c.Items)-1])
+	return fmt.Sprintf("%d: %s: state %d: %s conflict on %s: %s",
+		line, prod, c.State, c.Kind, strings.Join(c.Terminals, " "), strings.Join(items, "; "))
+}
+
+// position returns the line and name of the production an item comes from.
+func (a *Automaton) position(state, i int) (int, string) {
+	g := a.g
+	rule := g.rules[a.States[state].Items[i].Rule]
+	if rule.LHS == g.b.NumNonTerminals() {
+		rule = g.rules[g.byLHS[g.rules[g.accept].RHS[0].Index()][0]]
+	}
+	owner := g.b.Owner(rule.LHS)
+	if rule.Node != ebnf.NoNode {
+		return g.b.Compiled().Node(rule.Node).Pos.Line, g.b.Compiled().NonTerminal(owner)
+	}
+	return g.b.Compiled().Production(owner).Pos(), g.b.Compiled().NonTerminal(owner)
+}
+
+// conflicts returns the conflicts in every state.
+func (a *Automaton) conflicts() []Conflict {
+	g := a.g
+	var list []Conflict
+	for n, s := range a.States {
+		// the reduce items and shift for each terminal
+		reduces := make([][]int, g.eof+1)
+		shift := make([]bool, g.eof+1)
+		for _, t := range s.Transitions {
+			if t.Sym.IsTerminal() {
+				shift[t.Sym.Index()] = true
+			}
+		}
+		for i, item := range s.Items {
+			if item.Dot != len(g.rules[item.Rule].RHS) {
+				continue
+			}
+			for _, t := range s.Lookaheads[i].Elements() {
+				if t <= g.eof {
+					reduces[t] = append(reduces[t], i)
+				}
+			}
+		}
+
+		// group the terminals with the same actions
+		type group struct {
+			shift   bool
+			reduces []int
+			terms   ebnf.Bitset
+		}
+		var groups []*group
+		index := make(map[string]*group)
+		for t := 0; t <= g.eof; t++ {
+			if len(reduces[t]) == 0 || (len(reduces[t]) == 1 && !shift[t]) {
+				continue
+			}
+			key := fmt.Sprint(shift[t], reduces[t])
+			if index[key] == nil {
+				index[key] = &group{shift: shift[t], reduces: reduces[t], terms: ebnf.NewBitset(g.size())}
+				groups = append(groups, index[key])
+			}
+			index[key].terms.Set(t)
+		}
+		for _, grp := range groups {
+			c := Conflict{a: a, State: n, Kind: ReduceReduce, Terminals: g.terminals(grp.terms)}
+			if grp.shift {
+				c.Kind = ShiftReduce
+				for i, item := range s.Items {
+					if sym, ok := g.next(item); ok && sym.IsTerminal() && grp.terms.Has(sym.Index()) {
+						c.Items = append(c.Items, i)
+					}
+				}
+			}
+			c.Items = append(c.Items, grp.reduces...)
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Count returns the number of distinct conflicts of each kind. A conflict
+// is a terminal and the rules that can be reduced on it; one that shows
+// up in several states, as it does when LR(1) splits a state, is counted
+// once.
+func (a *Automaton) Count() (shiftReduce, reduceReduce int) {
+	seen := make(map[string]bool)
+	for _, c := range a.Conflicts {
+		var rules []int
+		for _, i := range c.Items {
+			if item := a.States[c.State].Items[i]; item.Dot == len(a.g.rules[item.Rule].RHS) {
+				rules = append(rules, item.Rule)
+			}
+		}
+		sort.Ints(rules)
+		for _, t := range c.Terminals {
+			key := fmt.Sprint(c.Kind, t, rules)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if c.Kind == ShiftReduce {
+				shiftReduce++
+			} else {
+				reduceReduce++
+			}
+		}
+	}
+	return shiftReduce, reduceReduce
+}
@@ -0,0 +1,486 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+// Package lr builds LR automata for EBNF grammars and reports the
+// conflicts that keep a grammar from being parsed bottom up.
+//
+// The grammar is first expanded into BNF by analysis.NewBNF, so options,
+// repetitions, and nested alternatives show up in the automaton as the
+// nonterminals created for them, such as "exp:1".
+package lr
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"sort"
+	"strings"
+)
+
+// A Method is a way of building an automaton.
+type Method int
+
+const (
+	LR0        Method = iota // LR(0), reducing on every terminal
+	SLR                      // SLR(1), reducing on the FOLLOW set of the rule
+	LALR                     // LALR(1), reducing on lookaheads propagated through the LR(0) states
+	LR1                      // canonical LR(1)
+	MinimalLR1               // LR(1) with states merged wherever that adds no conflicts
+)
+
+func (m Method) String() string {
+	switch m {
+	case LR0:
+		return "LR(0)"
+	case SLR:
+		return "SLR(1)"
+	case LALR:
+		return "LALR(1)"
+	case LR1:
+		return "LR(1)"
+	case MinimalLR1:
+		return "minimal LR(1)"
+	}
+	panic(fmt.Sprintf("assert(method != %d)", m))
+}
+
+// An Item is a rule with a dot showing how much of it has been seen.
+type Item struct {
+	Rule int // index of the rule; see Automaton.Rule
+	Dot  int // number of symbols before the dot
+}
+
+// A Transition is the state reached from a state after a symbol.
+type Transition struct {
+	Sym   analysis.Sym
+	State int
+}
+
+// A State is a set of items.
+type State struct {
+	// Items are the kernel items, sorted, followed by the closure items
+	// in the order they were added.
+	Items  []Item
+	Kernel int // number of kernel items
+	// Lookaheads are the terminals each item can be reduced on.
+	// The end of input is Automaton.EOF.
+	Lookaheads  []ebnf.Bitset
+	Transitions []Transition
+}
+
+// An Automaton is the LR automaton for a grammar.
+type Automaton struct {
+	Method    Method
+	States    []*State
+	Conflicts []Conflict
+	g         *grammar
+}
+
+// Build returns the automaton for the grammar with the start production.
+func Build(b *analysis.BNF, start string, method Method) (*Automaton, error) {
+	g, err := newGrammar(b, start)
+	if err != nil {
+		return nil, err
+	}
+	a := &Automaton{Method: method, g: g}
+	switch method {
+	case LR0, SLR:
+		a.States = g.lr0()
+		for _, s := range a.States {
+			s.Lookaheads = make([]ebnf.Bitset, len(s.Items))
+			for i, item := range s.Items {
+				if item.Dot != len(g.rules[item.Rule].RHS) {
+					continue
+				} else if method == LR0 {
+					s.Lookaheads[i] = ebnf.NewBitset(g.size())
+					for t := 0; t <= g.eof; t++ {
+						s.Lookaheads[i].Set(t)
+					}
+				} else {
+					s.Lookaheads[i] = g.follow[g.rules[item.Rule].LHS].Clone()
+				}
+			}
+		}
+	case LALR:
+		a.States = g.lalr(g.lr0())
+	case LR1:
+		a.States = g.lr1()
+	case MinimalLR1:
+		a.States = g.merge(g.lr1())
+	default:
+		panic(fmt.Sprintf("assert(method != %d)", method))
+	}
+	a.Conflicts = a.conflicts()
+	return a, nil
+}
+
+// grammar is a BNF grammar augmented with the rule "$accept = start".
+type grammar struct {
+	b        *analysis.BNF
+	rules    []analysis.Rule
+	byLHS    [][]int // rules by nonterminal
+	accept   int     // the accept rule
+	eof      int     // the end of input
+	nullable []bool
+	first    []ebnf.Bitset // by nonterminal
+	follow   []ebnf.Bitset // by nonterminal
+}
+
+// hash marks lookaheads to be propagated while computing LALR lookaheads.
+func (g *grammar) hash() int { return g.eof + 1 }
+
+// size returns the size of the lookahead sets.
+func (g *grammar) size() int { return g.eof + 2 }
+
+func newGrammar(b *analysis.BNF, start string) (*grammar, error) {
+	c := b.Compiled()
+	sym, ok := c.NonTerminalID(start)
+	if !ok || !c.Defined(sym) {
+		return nil, fmt.Errorf("no start production %q", start)
+	}
+	g := &grammar{b: b, eof: c.NumTerminals()}
+	g.rules = append(g.rules, b.Rules...)
+	g.accept = len(g.rules)
+	g.rules = append(g.rules, analysis.Rule{LHS: b.NumNonTerminals(), RHS: []analysis.Sym{analysis.NT(int(sym))}, Node: ebnf.NoNode})
+	g.byLHS = make([][]int, b.NumNonTerminals()+1)
+	for r, rule := range g.rules {
+		g.byLHS[rule.LHS] = append(g.byLHS[rule.LHS], r)
+	}
+
+	// nullable and FIRST
+	n := len(g.byLHS)
+	g.nullable = make([]bool, n)
+	g.first = make([]ebnf.Bitset, n)
+	g.follow = make([]ebnf.Bitset, n)
+	for i := range g.first {
+		g.first[i], g.follow[i] = ebnf.NewBitset(g.size()), ebnf.NewBitset(g.size())
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range g.rules {
+			first, nullable := g.firstOf(rule.RHS)
+			if g.first[rule.LHS].Union(first) {
+				changed = true
+			}
+			if nullable && !g.nullable[rule.LHS] {
+				g.nullable[rule.LHS], changed = true, true
+			}
+		}
+	}
+
+	// FOLLOW
+	g.follow[g.rules[g.accept].LHS].Set(g.eof)
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range g.rules {
+			for i, s := range rule.RHS {
+				if s.IsTerminal() {
+					continue
+				}
+				first, nullable := g.firstOf(rule.RHS[i+1:])
+				if g.follow[s.Index()].Union(first) {
+					changed = true
+				}
+				if nullable && g.follow[s.Index()].Union(g.follow[rule.LHS]) {
+					changed = true
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// firstOf returns the FIRST set of the symbols and true if they are nullable.
+func (g *grammar) firstOf(list []analysis.Sym) (ebnf.Bitset, bool) {
+	first := ebnf.NewBitset(g.size())
+	for _, s := range list {
+		if s.IsTerminal() {
+			first.Set(s.Index())
+			return first, false
+		}
+		first.Union(g.first[s.Index()])
+		if !g.nullable[s.Index()] {
+			return first, false
+		}
+	}
+	return first, true
+}
+
+// next returns the symbol after the dot, and false if the item is complete.
+func (g *grammar) next(item Item) (analysis.Sym, bool) {
+	rhs := g.rules[item.Rule].RHS
+	if item.Dot == len(rhs) {
+		return 0, false
+	}
+	return rhs[item.Dot], true
+}
+
+// closure returns the kernel items followed by the items they add.
+// If la is not nil, it holds the lookaheads of the kernel items and the
+// lookaheads of all the items are returned with them.
+func (g *grammar) closure(kernel []Item, la []ebnf.Bitset) ([]Item, []ebnf.Bitset) {
+	items := append([]Item(nil), kernel...)
+	index := make(map[Item]int)
+	for i, item := range items {
+		index[item] = i
+	}
+	var lookaheads []ebnf.Bitset
+	for _, set := range la {
+		lookaheads = append(lookaheads, set.Clone())
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(items); i++ {
+			s, ok := g.next(items[i])
+			if !ok || s.IsTerminal() {
+				continue
+			}
+			var add ebnf.Bitset
+			if la != nil {
+				first, nullable := g.firstOf(g.rules[items[i].Rule].RHS[items[i].Dot+1:])
+				if nullable {
+					first.Union(lookaheads[i])
+				}
+				add = first
+			}
+			for _, r := range g.byLHS[s.Index()] {
+				item := Item{Rule: r}
+				j, ok := index[item]
+				if !ok {
+					j, index[item] = len(items), len(items)
+					items = append(items, item)
+					if la != nil {
+						lookaheads = append(lookaheads, ebnf.NewBitset(g.size()))
+					}
+					changed = true
+				}
+				if la != nil && lookaheads[j].Union(add) {
+					changed = true
+				}
+			}
+		}
+	}
+	return items, lookaheads
+}
+
+// successors returns the symbols after the dots of the items, in order,
+// and the sorted kernel of the state reached after each one.
+// If la is not nil, it holds the lookaheads of the items and the
+// lookaheads of the kernel items are returned with them.
+func (g *grammar) successors(items []Item, la []ebnf.Bitset) ([]analysis.Sym, [][]Item, [][]ebnf.Bitset) {
+	var syms []analysis.Sym
+	kernels := make(map[analysis.Sym][]Item)
+	from := make(map[analysis.Sym][]int) // index of the item each kernel item comes from
+	for i, item := range items {
+		s, ok := g.next(item)
+		if !ok {
+			continue
+		}
+		if _, ok := kernels[s]; !ok {
+			syms = append(syms, s)
+		}
+		kernels[s] = append(kernels[s], Item{Rule: item.Rule, Dot: item.Dot + 1})
+		from[s] = append(from[s], i)
+	}
+	var list [][]Item
+	var lookaheads [][]ebnf.Bitset
+	for _, s := range syms {
+		kernel, from := kernels[s], from[s]
+		order := make([]int, len(kernel))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool {
+			a, b := kernel[order[i]], kernel[order[j]]
+			if a.Rule != b.Rule {
+				return a.Rule < b.Rule
+			}
+			return a.Dot < b.Dot
+		})
+		sorted := make([]Item, len(kernel))
+		var sets []ebnf.Bitset
+		for i, k := range order {
+			sorted[i] = kernel[k]
+			if la != nil {
+				sets = append(sets, la[from[k]].Clone())
+			}
+		}
+		list, lookaheads = append(list, sorted), append(lookaheads, sets)
+	}
+	return syms, list, lookaheads
+}
+
+func kernelKey(kernel []Item) string {
+	var sb strings.Builder
+	for _, item := range kernel {
+		fmt.Fprintf(&sb, "%d.%d ", item.Rule, item.Dot)
+	}
+	return sb.String()
+}
+
+// lr0 returns the LR(0) states. State 0 is the start state.
+func (g *grammar) lr0() []*State {
+	kernel := []Item{{Rule: g.accept}}
+	items, _ := g.closure(kernel, nil)
+	states := []*State{{Items: items, Kernel: 1}}
+	index := map[string]int{kernelKey(kernel): 0}
+	for i := 0; i < len(states); i++ {
+		syms, kernels, _ := g.successors(states[i].Items, nil)
+		for j, kernel := range kernels {
+			key := kernelKey(kernel)
+			to, ok := index[key]
+			if !ok {
+				items, _ := g.closure(kernel, nil)
+				to, index[key] = len(states), len(states)
+				states = append(states, &State{Items: items, Kernel: len(kernel)})
+			}
+			states[i].Transitions = append(states[i].Transitions, Transition{Sym: syms[j], State: to})
+		}
+	}
+	return states
+}
+
+// lalr sets the LALR(1) lookaheads of the LR(0) states by finding the
+// lookaheads each kernel item generates spontaneously and propagating
+// them along the transitions.
+func (g *grammar) lalr(states []*State) []*State {
+	type link struct{ state, item int }
+	la := make([][]ebnf.Bitset, len(states))
+	for i, s := range states {
+		la[i] = make([]ebnf.Bitset, s.Kernel)
+		for k := range la[i] {
+			la[i][k] = ebnf.NewBitset(g.size())
+		}
+	}
+	la[0][0].Set(g.eof)
+	propagate := make(map[link][]link)
+	for i, s := range states {
+		for k := 0; k < s.Kernel; k++ {
+			marker := ebnf.NewBitset(g.size())
+			marker.Set(g.hash())
+			items, lookaheads := g.closure(s.Items[k:k+1], []ebnf.Bitset{marker})
+			for j, item := range items {
+				sym, ok := g.next(item)
+				if !ok {
+					continue
+				}
+				to := s.transition(sym)
+				target := link{to, states[to].kernelIndex(Item{Rule: item.Rule, Dot: item.Dot + 1})}
+				for _, t := range lookaheads[j].Elements() {
+					if t == g.hash() {
+						propagate[link{i, k}] = append(propagate[link{i, k}], target)
+					} else {
+						la[target.state][target.item].Set(t)
+					}
+				}
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, s := range states {
+			for k := 0; k < s.Kernel; k++ {
+				for _, to := range propagate[link{i, k}] {
+					if la[to.state][to.item].Union(la[i][k]) {
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	for i, s := range states {
+		s.Items, s.Lookaheads = g.closure(s.Items[:s.Kernel], la[i])
+	}
+	return states
+}
+
+// transition returns the state reached after the symbol, or -1.
+func (s *State) transition(sym analysis.Sym) int {
+	for _, t := range s.Transitions {
+		if t.Sym == sym {
+			return t.State
+		}
+	}
+	return -1
+}
+
+// kernelIndex returns the index of the kernel item, or -1.
+func (s *State) kernelIndex(item Item) int {
+	for i, k := range s.Items[:s.Kernel] {
+		if k == item {
+			return i
+		}
+	}
+	return -1
+}
+
+// Rule returns the rule. The last rule is "$accept = start".
+func (a *Automaton) Rule(r int) analysis.Rule { return a.g.rules[r] }
+
+// NumRules returns the number of rules, including the accept rule.
+func (a *Automaton) NumRules() int { return len(a.g.rules) }
+
+// EOF returns the terminal for the end of input in lookahead sets.
+func (a *Automaton) EOF() int { return a.g.eof }
+
+// Name returns the name of the symbol.
+func (a *Automaton) Name(s analysis.Sym) string { return a.g.name(s) }
+
+func (g *grammar) name(s analysis.Sym) string {
+	if !s.IsTerminal() && s.Index() == g.b.NumNonTerminals() {
+		return "$accept"
+	}
+	return g.b.Name(s)
+}
+
+// terminals returns the names of the terminals in the set.
+func (g *grammar) terminals(set ebnf.Bitset) []string {
+	var names []string
+	for _, t := range set.Elements() {
+		if t == g.eof {
+			names = append(names, "$")
+		} else if t < g.eof {
+			names = append(names, g.b.Compiled().Terminal(ebnf.Symbol(t)))
+		}
+	}
+	return names
+}
+
+// ItemString returns the item of the state as "lhs = a . b [lookaheads]".
+func (a *Automaton) ItemString(state, i int) string {
+	g, s := a.g, a.States[state]
+	item := s.Items[i]
+	rule := g.rules[item.Rule]
+	var sb strings.Builder
+	sb.WriteString(g.name(analysis.NT(rule.LHS)))
+	sb.WriteString(" =")
+	for j, sym := range rule.RHS {
+		if j == item.Dot {
+			sb.WriteString(" .")
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(g.name(sym))
+	}
+	if item.Dot == len(rule.RHS) {
+		sb.WriteString(" .")
+		if s.Lookaheads != nil && s.Lookaheads[i] != nil {
+			fmt.Fprintf(&sb, " [%s]", strings.Join(g.terminals(s.Lookaheads[i]), " "))
+		}
+	}
+	return sb.String()
+}
+
+// StateString returns the items and transitions of the state, one per line.
+func (a *Automaton) StateString(state int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "state %d\n", state)
+	s := a.States[state]
+	for i := range s.Items {
+		fmt.Fprintf(&sb, "    %s\n", a.ItemString(state, i))
+	}
+	for _, t := range s.Transitions {
+		fmt.Fprintf(&sb, "    %s => %d\n", a.g.name(t.Sym), t.State)
+	}
+	return sb.String()
+}
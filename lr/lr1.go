@@ -0,0 +1,231 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lr
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"sort"
+	"strings"
+)
+
+// lr1 returns the canonical LR(1) states. A state is its kernel items
+// with their lookaheads, so states with the same LR(0) kernel but
+// different lookaheads are kept apart. State 0 is the start state.
+func (g *grammar) lr1() []*State {
+	kernel := []Item{{Rule: g.accept}}
+	la := []ebnf.Bitset{ebnf.NewBitset(g.size())}
+	la[0].Set(g.eof)
+	items, lookaheads := g.closure(kernel, la)
+	states := []*State{{Items: items, Kernel: 1, Lookaheads: lookaheads}}
+	index := map[string]int{lr1Key(kernel, la): 0}
+	for i := 0; i < len(states); i++ {
+		syms, kernels, las := g.successors(states[i].Items, states[i].Lookaheads)
+		for j, kernel := range kernels {
+			key := lr1Key(kernel, las[j])
+			to, ok := index[key]
+			if !ok {
+				items, lookaheads := g.closure(kernel, las[j])
+				to, index[key] = len(states), len(states)
+				states = append(states, &State{Items: items, Kernel: len(kernel), Lookaheads: lookaheads})
+			}
+			states[i].Transitions = append(states[i].Transitions, Transition{Sym: syms[j], State: to})
+		}
+	}
+	return states
+}
+
+func lr1Key(kernel []Item, la []ebnf.Bitset) string {
+	var sb strings.Builder
+	sb.WriteString(kernelKey(kernel))
+	for _, set := range la {
+		fmt.Fprint(&sb, set.Elements(), " ")
+	}
+	return sb.String()
+}
+
+// merge returns the LR(1) states with the states that have the same
+// LR(0) kernel merged wherever that adds no conflicts. Merged states
+// take the union of the lookaheads, as in LALR(1), so merging every
+// state with the same kernel would give the LALR(1) automaton.
+//
+// States are first put in groups greedily, in order. A group is then
+// split until all of its states go to the same groups on every symbol,
+// and a group that has a conflict none of its states had is split up.
+func (g *grammar) merge(states []*State) []*State {
+	group := make([]int, len(states))
+	var members [][]int
+	byKernel := make(map[string][]int) // groups by kernel
+	for i, s := range states {
+		key := kernelKey(s.Items[:s.Kernel])
+		found := false
+		for _, grp := range byKernel[key] {
+			if g.mergeable(states, append(members[grp][:len(members[grp]):len(members[grp])], i)) {
+				group[i], members[grp], found = grp, append(members[grp], i), true
+				break
+			}
+		}
+		if !found {
+			group[i] = len(members)
+			members = append(members, []int{i})
+			byKernel[key] = append(byKernel[key], group[i])
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		// split the groups whose states go to different groups
+		for split := true; split; {
+			split = false
+			for grp := 0; grp < len(members); grp++ {
+				parts := make(map[string][]int)
+				var keys []string
+				for _, i := range members[grp] {
+					var sb strings.Builder
+					for _, t := range states[i].Transitions {
+						fmt.Fprintf(&sb, "%d ", group[t.State])
+					}
+					key := sb.String()
+					if _, ok := parts[key]; !ok {
+						keys = append(keys, key)
+					}
+					parts[key] = append(parts[key], i)
+				}
+				if len(keys) == 1 {
+					continue
+				}
+				split, changed = true, true
+				members[grp] = parts[keys[0]]
+				for _, key := range keys[1:] {
+					for _, i := range parts[key] {
+						group[i] = len(members)
+					}
+					members = append(members, parts[key])
+				}
+			}
+		}
+		// splitting can leave a group with a conflict that came from a
+		// state that is no longer in it
+		for grp := 0; grp < len(members); grp++ {
+			if len(members[grp]) == 1 || g.mergeable(states, members[grp]) {
+				continue
+			}
+			changed = true
+			for _, i := range members[grp][1:] {
+				group[i] = len(members)
+				members = append(members, []int{i})
+			}
+			members[grp] = members[grp][:1]
+		}
+	}
+
+	// number the merged states in the order of their first states,
+	// so the start state stays 0
+	order := make([]int, len(members))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return members[order[i]][0] < members[order[j]][0] })
+	number := make([]int, len(members))
+	for n, grp := range order {
+		number[grp] = n
+	}
+	var merged []*State
+	for _, grp := range order {
+		first := states[members[grp][0]]
+		s := &State{Items: first.Items, Kernel: first.Kernel, Lookaheads: g.union(states, members[grp])}
+		for _, t := range first.Transitions {
+			s.Transitions = append(s.Transitions, Transition{Sym: t.Sym, State: number[group[t.State]]})
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// union returns the lookaheads of the states, which have the same items.
+func (g *grammar) union(states []*State, list []int) []ebnf.Bitset {
+	var la []ebnf.Bitset
+	for _, set := range states[list[0]].Lookaheads {
+		la = append(la, set.Clone())
+	}
+	for _, i := range list[1:] {
+		for k, set := range states[i].Lookaheads {
+			la[k].Union(set)
+		}
+	}
+	return la
+}
+
+// mergeable returns true if merging the states, which have the same
+// items, has no conflict that one of the states did not have.
+func (g *grammar) mergeable(states []*State, list []int) bool {
+	had := make(map[string]bool)
+	for _, i := range list {
+		for _, key := range g.conflictKeys(states[i], states[i].Lookaheads) {
+			had[key] = true
+		}
+	}
+	for _, key := range g.conflictKeys(states[list[0]], g.union(states, list)) {
+		if !had[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// conflictKeys returns the terminal and actions of each conflict in the
+// state with the lookaheads.
+func (g *grammar) conflictKeys(s *State, la []ebnf.Bitset) []string {
+	reduces := make([][]int, g.eof+1)
+	for i, item := range s.Items {
+		if item.Dot != len(g.rules[item.Rule].RHS) {
+			continue
+		}
+		for _, t := range la[i].Elements() {
+			if t <= g.eof {
+				reduces[t] = append(reduces[t], i)
+			}
+		}
+	}
+	var keys []string
+	for t, list := range reduces {
+		shift := s.transition(analysis.T(ebnf.Symbol(t))) != -1
+		if len(list) > 1 || (len(list) == 1 && shift) {
+			keys = append(keys, fmt.Sprint(t, shift, list))
+		}
+	}
+	return keys
+}
+
+// A Summary is the size of an automaton and the number of its distinct
+// conflicts, as counted by Automaton.Count. Since a conflict is counted
+// once however many states it is in, the conflicts LALR(1) has beyond
+// those of LR(1) are the ones added by merging states.
+type Summary struct {
+	Method       Method
+	States       int
+	ShiftReduce  int
+	ReduceReduce int
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%s: %d states, %d shift/reduce and %d reduce/reduce conflicts", s.Method, s.States, s.ShiftReduce, s.ReduceReduce)
+}
+
+// Compare builds the automaton for the grammar with every method and
+// returns their summaries, from LR(0) to minimal LR(1).
+func Compare(b *analysis.BNF, start string) ([]Summary, error) {
+	var list []Summary
+	for _, m := range []Method{LR0, SLR, LALR, LR1, MinimalLR1} {
+		a, err := Build(b, start, m)
+		if err != nil {
+			return nil, err
+		}
+		sr, rr := a.Count()
+		list = append(list, Summary{Method: m, States: len(a.States), ShiftReduce: sr, ReduceReduce: rr})
+	}
+	return list, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package lr
+
+import (
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"os"
+	"strings"
+	"testing"
+)
+
+func bnf(t *testing.T, src string) *analysis.BNF {
+	t.Helper()
+	grammar, errs := ebnf.Parse([]byte(src))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	c, err := ebnf.Compile(grammar)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	return analysis.NewBNF(c)
+}
+
+// the expression grammar and the grammar of assignments are from the dragon book.
+const (
+	expressions = `
+		e = e Plus t | t .
+		t = t Star f | f .
+		f = LParen e RParen | Id .`
+	assignments = `
+		s = l Eq r | r .
+		l = Star r | Id .
+		r = l .`
+	// notLALR is LR(1) but has a reduce/reduce conflict in LALR(1)
+	notLALR = `s = A e C | A f D | B f C | B e D . e = E . f = E .`
+)
+
+func TestBuild(t *testing.T) {
+	for i, tc := range []struct {
+		input     string
+		start     string
+		method    Method
+		states    int
+		conflicts []string
+	}{
+		{expressions, "e", LR0, 12, []string{
+			"2: e: state 1: shift/reduce conflict on Plus: e = e . Plus t; $accept = e . [Plus Star LParen RParen Id $]",
+			"2: e: state 2: shift/reduce conflict on Star: t = t . Star f; e = t . [Plus Star LParen RParen Id $]",
+			"2: e: state 9: shift/reduce conflict on Star: t = t . Star f; e = e Plus t . [Plus Star LParen RParen Id $]",
+		}},
+		{expressions, "e", SLR, 12, nil},
+		{expressions, "e", LALR, 12, nil},
+		{assignments, "s", SLR, 10, []string{
+			"4: r: state 2: shift/reduce conflict on Eq: s = l . Eq r; r = l . [Eq $]",
+		}},
+		{assignments, "s", LALR, 10, nil},
+		// the dangling else is a conflict for every method
+		{`s = If C Then s [ Else s ] | X .`, "s", LALR, 10, []string{
+			"1: s: state 6: shift/reduce conflict on Else: s:1 = . Else s; s:1 = . [Else $]",
+		}},
+		// options and repetitions are expanded into new nonterminals
+		{`s = { A } [ B ] .`, "s", LALR, 6, nil},
+		// canonical LR(1) splits states that LALR(1) merges
+		{assignments, "s", LR1, 14, nil},
+		{assignments, "s", MinimalLR1, 10, nil},
+		{notLALR, "s", LALR, 13, []string{
+			"1: f: state 6: reduce/reduce conflict on C D: e = E . [C D]; f = E . [C D]",
+		}},
+		{notLALR, "s", LR1, 14, nil},
+		{notLALR, "s", MinimalLR1, 14, nil},
+	} {
+		a, err := Build(bnf(t, tc.input), tc.start, tc.method)
+		if err != nil {
+			t.Fatalf("%d: Build failed: %v", i+1, err)
+		}
+		if len(a.States) != tc.states {
+			t.Errorf("%d: %s: want %d states, got %d", i+1, tc.method, tc.states, len(a.States))
+		}
+		var got []string
+		for _, c := range a.Conflicts {
+			got = append(got, c.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.conflicts, "\n") {
+			t.Errorf("%d: %s: want\n%s\ngot\n%s", i+1, tc.method, strings.Join(tc.conflicts, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	if _, err := Build(bnf(t, expressions), "x", LALR); err == nil {
+		t.Errorf("Build should fail for an undefined start")
+	}
+}
+
+func TestBuildLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bnf(t, string(input))
+	var counts []int
+	for _, method := range []Method{LR0, SLR, LALR} {
+		a, err := Build(b, "chunk", method)
+		if err != nil {
+			t.Fatalf("%s: Build failed: %v", method, err)
+		}
+		if len(a.States) != 200 {
+			t.Errorf("%s: want 200 states, got %d", method, len(a.States))
+		}
+		sr, rr := a.Count()
+		counts = append(counts, sr+rr)
+	}
+	// each method is at least as strong as the one before
+	if !(counts[0] > counts[1] && counts[1] >= counts[2] && counts[2] > 0) {
+		t.Errorf("want fewer conflicts for stronger methods, got %v", counts)
+	}
+
+	// merging the LR(1) states brings them back down toward the LR(0) count
+	lr1, err := Build(b, "chunk", LR1)
+	if err != nil {
+		t.Fatalf("%s: Build failed: %v", LR1, err)
+	}
+	minimal, err := Build(b, "chunk", MinimalLR1)
+	if err != nil {
+		t.Fatalf("%s: Build failed: %v", MinimalLR1, err)
+	}
+	if !(len(lr1.States) > 200 && len(minimal.States) >= 200 && len(minimal.States) < len(lr1.States)) {
+		t.Errorf("want LR(1) > minimal LR(1) >= 200 states, got %d and %d", len(lr1.States), len(minimal.States))
+	}
+
+	// the conflicts come from the ambiguity of the grammar, so splitting
+	// the states neither adds nor removes any
+	sr, rr := lr1.Count()
+	if sr+rr != counts[2] {
+		t.Errorf("want %d conflicts for %s as for %s, got %d", counts[2], LR1, LALR, sr+rr)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	list, err := Compare(bnf(t, notLALR), "s")
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	var got []string
+	for _, s := range list {
+		got = append(got, s.String())
+	}
+	want := []string{
+		"LR(0): 13 states, 0 shift/reduce and 6 reduce/reduce conflicts",
+		"SLR(1): 13 states, 0 shift/reduce and 2 reduce/reduce conflicts",
+		"LALR(1): 13 states, 0 shift/reduce and 2 reduce/reduce conflicts",
+		"LR(1): 14 states, 0 shift/reduce and 0 reduce/reduce conflicts",
+		"minimal LR(1): 14 states, 0 shift/reduce and 0 reduce/reduce conflicts",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -13,8 +13,17 @@ import "github.com/mdhender/ebnf/tokens"
 type (
 	// A Production node represents an EBNF production.
 	Production struct {
-		Name *Name
-		Expr Expression
+		Doc         []*Comment // comments before the production and within its expression, and those of a rejected duplicate; or nil
+		Name        *Name
+		Expr        Expression
+		LineComment *Comment   // comment following the terminator on the same line; or nil
+		Trailer     []*Comment // comments after the last production in the input; or nil
+		end         *tokens.Token
+	}
+
+	// A Comment node represents a single ";" comment.
+	Comment struct {
+		tok *tokens.Token
 	}
 
 	// An Expression node represents a production expression.
@@ -48,18 +57,21 @@ type (
 	Group struct {
 		tok  *tokens.Token
 		Body Expression // (body)
+		end  *tokens.Token
 	}
 
 	// An Option node represents an optional expression.
 	Option struct {
 		tok  *tokens.Token
 		Body Expression // [body]
+		end  *tokens.Token
 	}
 
 	// A Repetition node represents a repeated expression.
 	Repetition struct {
 		tok  *tokens.Token
 		Body Expression // {body}
+		end  *tokens.Token
 	}
 
 	// A Bad node stands for pieces of source code that lead to a parse error.
@@ -77,6 +89,41 @@ func (x *Group) Pos() int      { return x.tok.Line() }
 func (x *Option) Pos() int     { return x.tok.Line() }
 func (x *Repetition) Pos() int { return x.tok.Line() }
 func (x *Production) Pos() int { return x.Name.Pos() }
-func (x *Bad) Pos() int        { return x.Pos() }
+func (x *Bad) Pos() int        { return x.tok.Line() }
+func (x *Comment) Pos() int    { return x.tok.Line() }
+
+// Position returns the line and column of the first token in the expression.
+// It returns the zero Position for an empty expression or one without tokens.
+func Position(x Expression) tokens.Position {
+	var tok *tokens.Token
+	switch x := x.(type) {
+	case Alternative:
+		return Position(x[0])
+	case Sequence:
+		return Position(x[0])
+	case *Name:
+		tok = x.tok
+	case *Literal:
+		tok = x.tok
+	case *Group:
+		tok = x.tok
+	case *Option:
+		tok = x.tok
+	case *Repetition:
+		tok = x.tok
+	case *Bad:
+		tok = x.tok
+	case *Production:
+		tok = x.Name.tok
+	case *Comment:
+		tok = x.tok
+	}
+	if tok == nil {
+		return tokens.Position{}
+	}
+	return tok.Pos
+}
 
-func (x *Name) String() string { return string(x.tok.Text) }
+func (x *Name) String() string    { return string(x.tok.Text) }
+func (x *Literal) String() string { return string(x.tok.Text) }
+func (x *Comment) String() string { return string(x.tok.Text) }
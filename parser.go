@@ -16,7 +16,7 @@ import (
 // Errors are reported for incorrect syntax and if a production
 // is declared more than once.
 func Parse(input []byte) (Grammar, []error) {
-	toks := scanners.Scan(input)
+	toks := scanners.ScanMode(input, scanners.ScanComments)
 
 	var p parser
 	grammar := p.parse(toks)
@@ -30,11 +30,13 @@ type parser struct {
 	lit    string        // token literal
 	tokens []*tokens.Token
 	errors errorList
+
+	comments []*Comment // comments not yet attached to a production
 }
 
 // parse parses a grammar
 // --> grammar     ::= production { production } .
-// --> production  ::= NONTERMINAL EQ [ expression ] TERMINATOR .
+// --> production  ::= ( NONTERMINAL | TERMINAL ) EQ [ expression ] TERMINATOR .
 // --> expression  ::= sequence { OR sequence } .
 // --> sequence    ::= term { term } .
 // --> term        ::= NONTERMINAL | TERMINAL | group | option | repetition .
@@ -51,30 +53,49 @@ func (p *parser) parse(toks []*tokens.Token) (grammar Grammar) {
 	p.next()
 
 	grammar = make(Grammar)
+	var last *Production
 	for p.tok != p.eof {
 		prod := p.parseProduction()
-		name := prod.Name.String()
-		if def, found := grammar[name]; found {
-			p.error("%d: %s: defined line %d", prod.Name.tok.Line(), def.Name.String(), def.Name.tok.Line())
+		if err := grammar.define(prod); err != nil {
+			p.errors = append(p.errors, err)
+			// keep the comments of the duplicate on the production that was kept
+			kept := grammar[prod.Name.String()]
+			kept.Doc = append(kept.Doc, prod.Doc...)
+			if prod.LineComment != nil {
+				kept.Doc = append(kept.Doc, prod.LineComment)
+			}
 			continue
 		}
-		grammar[name] = prod
+		last = prod
+	}
+
+	// comments after the last production belong to it
+	if last != nil && len(p.comments) != 0 {
+		last.Trailer, p.comments = p.comments, nil
 	}
 
 	return grammar
 }
 
 // parseProduction parses
-// --> production  ::= NONTERMINAL EQ [ expression ] TERMINATOR .
+// --> production  ::= ( NONTERMINAL | TERMINAL ) EQ [ expression ] TERMINATOR .
+// Comments before the name and within the expression are attached to
+// the production as its Doc.
 func (p *parser) parseProduction() *Production {
-	name := p.parseNonTerminal()
+	prod := &Production{Doc: p.comments}
+	p.comments = nil
+	prod.Name = p.parseProductionName()
 	p.expect(tokens.EQ)
-	var expr Expression
 	if p.tok.Kind != tokens.TERMINATOR {
-		expr = p.parseExpression()
+		prod.Expr = p.parseExpression()
 	}
+	prod.Doc, p.comments = append(prod.Doc, p.comments...), nil
+	prod.end = p.tok
 	p.expect(tokens.TERMINATOR)
-	return &Production{Name: name, Expr: expr}
+	if len(p.comments) != 0 && p.comments[0].tok.Line() == prod.end.Line() {
+		prod.LineComment, p.comments = p.comments[0], p.comments[1:]
+	}
+	return prod
 }
 
 // parseExpression parses
@@ -139,17 +160,20 @@ func (p *parser) parseTerm() (x Expression) {
 
 	case tokens.START_GROUP:
 		p.next()
-		x = &Group{tok: tok, Body: p.parseExpression()}
+		body := p.parseExpression()
+		x = &Group{tok: tok, Body: body, end: p.tok}
 		p.expect(tokens.END_GROUP)
 
 	case tokens.START_OPTION:
 		p.next()
-		x = &Option{tok: tok, Body: p.parseExpression()}
+		body := p.parseExpression()
+		x = &Option{tok: tok, Body: body, end: p.tok}
 		p.expect(tokens.END_OPTION)
 
 	case tokens.START_REPETITION:
 		p.next()
-		x = &Repetition{tok: tok, Body: p.parseExpression()}
+		body := p.parseExpression()
+		x = &Repetition{tok: tok, Body: body, end: p.tok}
 		p.expect(tokens.END_REPETITION)
 	}
 
@@ -163,6 +187,18 @@ func (p *parser) parseNonTerminal() *Name {
 	return &Name{tok: tok}
 }
 
+// parseProductionName parses the NONTERMINAL or TERMINAL that names a
+// production. A production named by a TERMINAL is lexical.
+func (p *parser) parseProductionName() *Name {
+	tok := p.tok
+	if p.tok.Kind == tokens.TERMINAL {
+		p.next()
+	} else {
+		p.expect(tokens.NONTERMINAL)
+	}
+	return &Name{tok: tok}
+}
+
 // parseTerminal parses a TERMINAL.
 func (p *parser) parseTerminal() *Literal {
 	tok := p.tok
@@ -176,10 +212,15 @@ func (p *parser) parseTerminal() *Literal {
 
 // next returns the next token from the scanner.
 // it never advances past the last token in the scanner.
+// comments are saved for the next production.
 func (p *parser) next() {
-	if p.tok != p.eof {
+	for p.tok != p.eof {
 		p.tok = p.tokens[p.pos]
 		p.pos = p.pos + 1
+		if p.tok.Kind != tokens.COMMENT {
+			break
+		}
+		p.comments = append(p.comments, &Comment{tok: p.tok})
 	}
 	p.lit = string(p.tok.Text)
 }
@@ -0,0 +1,226 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fprint writes the grammar to w in canonical form.
+//
+// Productions are written in source order. A blank line separates
+// productions that were separated by blank lines in the source, and
+// the "=" of every production in a block of adjacent productions is
+// aligned. A production with more than one alternative is written
+// with one alternative per line and the "|" under the "=".
+// Groups are only written where they are needed to keep alternatives
+// inside a sequence together.
+//
+// Comments are kept. Comments from within an expression are written
+// on the lines before the production.
+func Fprint(w io.Writer, grammar Grammar) error {
+	var p printer
+	for i, block := range blocks(grammar) {
+		if i != 0 {
+			p.WriteByte('\n')
+		}
+		width := 0
+		for _, prod := range block {
+			if n := len(prod.Name.String()); n > width {
+				width = n
+			}
+		}
+		for _, prod := range block {
+			p.production(prod, width)
+		}
+	}
+	if p.err != nil {
+		return p.err
+	}
+	_, err := w.Write(p.Bytes())
+	return err
+}
+
+// ExprString returns the expression in the canonical form used by Fprint.
+// An empty expression is returned as an empty string.
+func ExprString(x Expression) string {
+	var p printer
+	p.expr(x, inBody)
+	if p.err != nil {
+		return fmt.Sprintf("<%v>", p.err)
+	}
+	return p.String()
+}
+
+// blocks returns the productions in source order, split into
+// runs of productions that are not separated by blank lines.
+func blocks(grammar Grammar) (list [][]*Production) {
+	var block []*Production
+	for _, prod := range Productions(grammar) {
+		if len(block) != 0 && firstLine(prod) > lastLine(block[len(block)-1])+1 {
+			list, block = append(list, block), nil
+		}
+		block = append(block, prod)
+	}
+	if len(block) != 0 {
+		list = append(list, block)
+	}
+	return list
+}
+
+// firstLine returns the line of the first comment or token in the production.
+func firstLine(prod *Production) int {
+	if len(prod.Doc) != 0 && prod.Doc[0].Pos() < prod.Pos() {
+		return prod.Doc[0].Pos()
+	}
+	return prod.Pos()
+}
+
+// lastLine returns the line of the terminator of the production.
+// For productions that were not parsed, it is the line of the last token.
+func lastLine(prod *Production) int {
+	if prod.end != nil {
+		return prod.end.Line()
+	}
+	line := prod.Pos()
+	var walk func(x Expression)
+	walk = func(x Expression) {
+		switch x := x.(type) {
+		case Alternative:
+			for _, e := range x {
+				walk(e)
+			}
+		case Sequence:
+			for _, e := range x {
+				walk(e)
+			}
+		case *Group:
+			walk(x.Body)
+		case *Option:
+			walk(x.Body)
+		case *Repetition:
+			walk(x.Body)
+		}
+		if x != nil && x.Pos() > line {
+			line = x.Pos()
+		}
+	}
+	walk(prod.Expr)
+	return line
+}
+
+type printer struct {
+	bytes.Buffer
+	err error
+}
+
+// context of an expression, used to decide if a group is needed.
+type context int
+
+const (
+	inBody     context = iota // body of a production, group, option, or repetition
+	inAlt                     // element of an alternative
+	inSequence                // element of a sequence
+)
+
+// production writes the production with the name padded to width.
+func (p *printer) production(prod *Production, width int) {
+	p.comments(prod.Doc, prod.Pos())
+	name := prod.Name.String()
+	p.WriteString(name)
+	p.WriteString(strings.Repeat(" ", width-len(name)))
+	p.WriteString(" =")
+	if prod.Expr != nil {
+		for i, alt := range Alternatives(prod.Expr) {
+			if i == 0 {
+				p.WriteByte(' ')
+			} else {
+				p.WriteByte('\n')
+				p.WriteString(strings.Repeat(" ", width+1))
+				p.WriteString("| ")
+			}
+			p.expr(sequence(alt), inAlt)
+		}
+	}
+	p.WriteString(" .")
+	if prod.LineComment != nil {
+		p.WriteByte(' ')
+		p.WriteString(prod.LineComment.String())
+	}
+	p.WriteByte('\n')
+	if len(prod.Trailer) != 0 {
+		if prod.Trailer[0].Pos() > lastLine(prod)+1 {
+			p.WriteByte('\n')
+		}
+		p.comments(prod.Trailer, 0)
+	}
+}
+
+// comments writes each comment on its own line, keeping a single blank
+// line wherever the source had one or more. If next is not zero, it is
+// the line of the token that follows the comments.
+func (p *printer) comments(list []*Comment, next int) {
+	for i, c := range list {
+		p.WriteString(c.String())
+		p.WriteByte('\n')
+		if i+1 < len(list) {
+			if list[i+1].Pos() > c.Pos()+1 {
+				p.WriteByte('\n')
+			}
+		} else if next != 0 && next > c.Pos()+1 {
+			p.WriteByte('\n')
+		}
+	}
+}
+
+func (p *printer) expr(x Expression, ctx context) {
+	switch x := x.(type) {
+	case nil:
+		// empty expression
+	case Alternative:
+		if ctx == inSequence {
+			p.WriteByte('(')
+		}
+		for i, e := range x {
+			if i != 0 {
+				p.WriteString(" | ")
+			}
+			p.expr(e, inAlt)
+		}
+		if ctx == inSequence {
+			p.WriteByte(')')
+		}
+	case Sequence:
+		for i, e := range x {
+			if i != 0 {
+				p.WriteByte(' ')
+			}
+			p.expr(e, inSequence)
+		}
+	case *Name:
+		p.WriteString(x.String())
+	case *Literal:
+		p.WriteString(x.String())
+	case *Group:
+		p.expr(x.Body, ctx)
+	case *Option:
+		p.WriteByte('[')
+		p.expr(x.Body, inBody)
+		p.WriteByte(']')
+	case *Repetition:
+		p.WriteByte('{')
+		p.expr(x.Body, inBody)
+		p.WriteByte('}')
+	case *Bad:
+		if p.err == nil {
+			p.err = fmt.Errorf("%d: %v", x.Pos(), x.err)
+		}
+	default:
+		panic(fmt.Sprintf("internal error: unexpected type %T", x))
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestFprint(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		input  string
+		expect string
+	}{
+		{id: 1, input: "program = .", expect: "program = .\n"},
+		{id: 2,
+			input:  "; header\n\n; doc\nprogram = song . ; line\nsong = { (note) } note (A | B) ( C D ) [ (E|F) ] .\nnote = (A | (B | C)) .\n\n; end\n",
+			expect: "; header\n\n; doc\nprogram = song . ; line\nsong    = {note} note (A | B) C D [E | F] .\nnote    = A\n        | B\n        | C .\n\n; end\n",
+		},
+		{id: 3,
+			input:  "a = B\n  ; inside\n  | C .\n\n\n\nb = D .",
+			expect: "; inside\na = B\n  | C .\n\nb = D .\n",
+		},
+	} {
+		grammar, errs := Parse([]byte(tc.input))
+		if errs != nil {
+			t.Errorf("%d: Parse failed: %v", tc.id, errs)
+			continue
+		}
+		var got bytes.Buffer
+		if err := Fprint(&got, grammar); err != nil {
+			t.Errorf("%d: Fprint failed: %v", tc.id, err)
+		} else if got.String() != tc.expect {
+			t.Errorf("%d: want\n%s\ngot\n%s", tc.id, tc.expect, got.String())
+		}
+	}
+}
+
+func TestFprintDuplicate(t *testing.T) {
+	grammar, errs := Parse([]byte("; first\na = B .\n; second\na = C . ; line\nb = D ."))
+	if len(errs) != 1 {
+		t.Fatalf("want 1 error, got %v", errs)
+	}
+	var got bytes.Buffer
+	if err := Fprint(&got, grammar); err != nil {
+		t.Fatalf("Fprint failed: %v", err)
+	}
+	want := "; first\n\n; second\n; line\na = B .\n\nb = D .\n"
+	if got.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got.String())
+	}
+}
+
+func TestFprintIdempotent(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&first, &second} {
+		grammar, errs := Parse(input)
+		if errs != nil {
+			t.Fatalf("Parse failed: %v", errs)
+		}
+		if err := Fprint(buf, grammar); err != nil {
+			t.Fatalf("Fprint failed: %v", err)
+		}
+		input = buf.Bytes()
+	}
+	if first.String() != second.String() {
+		t.Errorf("Fprint is not idempotent:\n%s\n%s", first.String(), second.String())
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf/tokens"
+)
+
+// ----------------------------------------------------------------------------
+// Constructors
+
+// NewName returns a reference to the production with the given name.
+func NewName(pos tokens.Position, name string) *Name {
+	return &Name{tok: &tokens.Token{Pos: pos, Kind: tokens.NONTERMINAL, Text: []byte(name)}}
+}
+
+// NewLiteral returns a reference to the terminal with the given name.
+func NewLiteral(pos tokens.Position, name string) *Literal {
+	return &Literal{tok: &tokens.Token{Pos: pos, Kind: tokens.TERMINAL, Text: []byte(name)}}
+}
+
+// NewGroup returns the grouped expression (body).
+func NewGroup(pos tokens.Position, body Expression) *Group {
+	return &Group{tok: &tokens.Token{Pos: pos, Kind: tokens.START_GROUP}, Body: body}
+}
+
+// NewOption returns the optional expression [body].
+func NewOption(pos tokens.Position, body Expression) *Option {
+	return &Option{tok: &tokens.Token{Pos: pos, Kind: tokens.START_OPTION}, Body: body}
+}
+
+// NewRepetition returns the repeated expression {body}.
+func NewRepetition(pos tokens.Position, body Expression) *Repetition {
+	return &Repetition{tok: &tokens.Token{Pos: pos, Kind: tokens.START_REPETITION}, Body: body}
+}
+
+// ----------------------------------------------------------------------------
+// Clone
+
+// Clone returns a deep copy of the grammar.
+// Nothing, not even a token, is shared between the grammar and the copy.
+func Clone(grammar Grammar) Grammar {
+	if grammar == nil {
+		return nil
+	}
+	clone := make(Grammar, len(grammar))
+	for name, prod := range grammar {
+		clone[name] = CloneProduction(prod)
+	}
+	return clone
+}
+
+// CloneProduction returns a deep copy of the production.
+func CloneProduction(prod *Production) *Production {
+	if prod == nil {
+		return nil
+	}
+	return &Production{
+		Doc:         cloneComments(prod.Doc),
+		Name:        &Name{tok: cloneToken(prod.Name.tok)},
+		Expr:        CloneExpr(prod.Expr),
+		LineComment: cloneComment(prod.LineComment),
+		Trailer:     cloneComments(prod.Trailer),
+		end:         cloneToken(prod.end),
+	}
+}
+
+// CloneExpr returns a deep copy of the expression.
+func CloneExpr(x Expression) Expression {
+	switch x := x.(type) {
+	case nil:
+		return nil
+	case Alternative:
+		list := make(Alternative, len(x))
+		for i, e := range x {
+			list[i] = CloneExpr(e)
+		}
+		return list
+	case Sequence:
+		list := make(Sequence, len(x))
+		for i, e := range x {
+			list[i] = CloneExpr(e)
+		}
+		return list
+	case *Name:
+		return &Name{tok: cloneToken(x.tok)}
+	case *Literal:
+		return &Literal{tok: cloneToken(x.tok)}
+	case *Group:
+		return &Group{tok: cloneToken(x.tok), Body: CloneExpr(x.Body), end: cloneToken(x.end)}
+	case *Option:
+		return &Option{tok: cloneToken(x.tok), Body: CloneExpr(x.Body), end: cloneToken(x.end)}
+	case *Repetition:
+		return &Repetition{tok: cloneToken(x.tok), Body: CloneExpr(x.Body), end: cloneToken(x.end)}
+	case *Bad:
+		return &Bad{tok: cloneToken(x.tok), err: x.err}
+	}
+	panic(fmt.Sprintf("internal error: unexpected type %T", x))
+}
+
+func cloneComment(c *Comment) *Comment {
+	if c == nil {
+		return nil
+	}
+	return &Comment{tok: cloneToken(c.tok)}
+}
+
+func cloneComments(list []*Comment) []*Comment {
+	if list == nil {
+		return nil
+	}
+	clone := make([]*Comment, len(list))
+	for i, c := range list {
+		clone[i] = cloneComment(c)
+	}
+	return clone
+}
+
+func cloneToken(tok *tokens.Token) *tokens.Token {
+	if tok == nil {
+		return nil
+	}
+	clone := *tok
+	clone.Text, clone.Leading, clone.Trailing = cloneBytes(tok.Text), cloneBytes(tok.Leading), cloneBytes(tok.Trailing)
+	return &clone
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// ----------------------------------------------------------------------------
+// Apply
+
+// An ApplyFunc is invoked by Apply for each node, before and after
+// the node's children are visited. See Apply for the meaning of the result.
+type ApplyFunc func(c *Cursor) bool
+
+// A Cursor describes the node being visited by Apply and lets the
+// ApplyFunc change it.
+type Cursor struct {
+	parent   Expression
+	index    int
+	node     Expression
+	before   []Expression
+	after    []Expression
+	edited   bool // node was replaced or deleted
+	replaced bool // node was replaced and its children not visited since
+	delete   bool
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Expression { return c.node }
+
+// Parent returns the parent of the current node, or nil for the root.
+// The parent is the node as it was before any of its children were changed.
+func (c *Cursor) Parent() Expression { return c.parent }
+
+// Index returns the index of the current node in its parent Alternative
+// or Sequence, or -1 if the parent is not an Alternative or Sequence.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current node with x.
+// The children of x are visited next.
+// Replacing the node with an empty Alternative or Sequence deletes it.
+// It panics if x is nil.
+func (c *Cursor) Replace(x Expression) {
+	if x == nil {
+		panic("Replace: nil expression")
+	}
+	c.node, c.edited, c.replaced, c.delete = x, true, true, false
+}
+
+// Delete removes the current node from its parent.
+// A Group, Option, or Repetition whose body is deleted is removed as well,
+// and so is an Alternative or Sequence that has no elements left.
+// Deleting the root leaves an empty expression.
+func (c *Cursor) Delete() {
+	c.edited, c.delete = true, true
+}
+
+// InsertBefore inserts x before the current node in its parent.
+// It panics if x is nil or the parent is not an Alternative or Sequence.
+func (c *Cursor) InsertBefore(x Expression) {
+	c.mustBeInList("InsertBefore")
+	c.mustNotBeNil("InsertBefore", x)
+	c.before = append(c.before, x)
+}
+
+// InsertAfter inserts x after the current node in its parent,
+// after any nodes inserted by earlier calls.
+// It panics if x is nil or the parent is not an Alternative or Sequence.
+func (c *Cursor) InsertAfter(x Expression) {
+	c.mustBeInList("InsertAfter")
+	c.mustNotBeNil("InsertAfter", x)
+	c.after = append(c.after, x)
+}
+
+func (c *Cursor) mustBeInList(op string) {
+	if c.index < 0 {
+		panic(fmt.Sprintf("%s: parent is not an Alternative or Sequence", op))
+	}
+}
+
+func (c *Cursor) mustNotBeNil(op string, x Expression) {
+	if x == nil {
+		panic(fmt.Sprintf("%s: nil expression", op))
+	}
+}
+
+// Apply traverses the expression recursively, starting with root,
+// and returns the rewritten expression.
+//
+// If pre is not nil, it is called for each node before the node's children
+// are traversed. If pre returns false, the children are not traversed
+// and post is not called for that node.
+// If post is not nil, it is called for each node after its children are
+// traversed. If post returns false, the traversal stops and Apply returns
+// immediately with the changes made so far.
+//
+// Apply never modifies its input. When a node changes, the nodes on the
+// path from the root to it are copied, so the result shares every
+// unchanged node with the input. Use Clone first to get a result that
+// shares nothing.
+//
+// Every Alternative and Sequence in the result is non-empty and, as with
+// Parse, one with a single element is replaced by that element. This holds
+// for the input, replacements, and inserted nodes alike: an empty one is
+// deleted. Apply returns nil if nothing is left of the root.
+func Apply(root Expression, pre, post ApplyFunc) Expression {
+	a := &application{pre: pre, post: post}
+	nodes, _ := a.slot(nil, -1, root)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+type application struct {
+	pre, post ApplyFunc
+	stop      bool
+}
+
+// slot visits the node at the index in the parent. It returns the
+// nodes that replace it and true if they are different from the node.
+func (a *application) slot(parent Expression, index int, node Expression) ([]Expression, bool) {
+	if a.stop || node == nil {
+		return []Expression{node}, false
+	}
+	c := &Cursor{parent: parent, index: index, node: node}
+	if a.pre == nil || a.pre(c) {
+		c.replaced = false
+		if !c.delete {
+			if x, changed := a.children(c.node); changed {
+				c.node, c.edited = x, true
+				c.delete = x == nil
+			}
+		}
+		if a.post != nil && !c.delete && !a.stop && !a.post(c) {
+			a.stop = true
+		}
+	}
+	if c.replaced && !c.delete {
+		c.node = normalize(c.node)
+		c.delete = c.node == nil
+	}
+	var nodes []Expression
+	for _, x := range c.before {
+		if x = normalize(x); x != nil {
+			nodes = append(nodes, x)
+		}
+	}
+	if !c.delete {
+		nodes = append(nodes, c.node)
+	}
+	for _, x := range c.after {
+		if x = normalize(x); x != nil {
+			nodes = append(nodes, x)
+		}
+	}
+	return nodes, c.edited || len(c.before) != 0 || len(c.after) != 0
+}
+
+// normalize returns x with its empty Alternatives and Sequences deleted
+// and those with a single element replaced by that element, or nil if
+// nothing is left. It is used for the nodes Apply does not visit.
+func normalize(x Expression) Expression {
+	x, _ = (&application{}).children(x)
+	return x
+}
+
+// children visits the children of the node. It returns the node with its
+// new children, or nil if none are left, and true if anything changed.
+func (a *application) children(x Expression) (Expression, bool) {
+	switch x := x.(type) {
+	case Alternative:
+		list, changed := a.list(x, x)
+		if len(list) < 2 {
+			return first(list), true
+		} else if !changed {
+			return x, false
+		}
+		return Alternative(list), true
+	case Sequence:
+		list, changed := a.list(x, x)
+		if len(list) < 2 {
+			return first(list), true
+		} else if !changed {
+			return x, false
+		}
+		return Sequence(list), true
+	case *Group:
+		if body, changed := a.body(x, x.Body); !changed {
+			return x, false
+		} else if body != nil {
+			y := *x
+			y.Body = body
+			return &y, true
+		}
+		return nil, true
+	case *Option:
+		if body, changed := a.body(x, x.Body); !changed {
+			return x, false
+		} else if body != nil {
+			y := *x
+			y.Body = body
+			return &y, true
+		}
+		return nil, true
+	case *Repetition:
+		if body, changed := a.body(x, x.Body); !changed {
+			return x, false
+		} else if body != nil {
+			y := *x
+			y.Body = body
+			return &y, true
+		}
+		return nil, true
+	}
+	return x, false
+}
+
+// list visits the elements of an Alternative or Sequence.
+func (a *application) list(parent Expression, elements []Expression) (list []Expression, changed bool) {
+	for i, e := range elements {
+		nodes, ch := a.slot(parent, i, e)
+		list, changed = append(list, nodes...), changed || ch
+	}
+	return list, changed
+}
+
+// body visits the body of a Group, Option, or Repetition.
+func (a *application) body(parent, body Expression) (Expression, bool) {
+	nodes, changed := a.slot(parent, -1, body)
+	return first(nodes), changed
+}
+
+func first(list []Expression) Expression {
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
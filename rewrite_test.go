@@ -0,0 +1,191 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"github.com/mdhender/ebnf/tokens"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar, errs := Parse(input)
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	clone := Clone(grammar)
+	if !reflect.DeepEqual(grammar, clone) {
+		t.Fatalf("Clone differs from grammar")
+	}
+	clone["chunk"].Name.tok.Text[0] = 'C'
+	if grammar["chunk"].Name.String() != "chunk" {
+		t.Errorf("Clone shares tokens with grammar")
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		input  string
+		pre    ApplyFunc
+		post   ApplyFunc
+		expect string
+	}{
+		// rename a terminal
+		{id: 1, input: "A b {A | C}", pre: func(c *Cursor) bool {
+			if lit, ok := c.Node().(*Literal); ok && lit.String() == "A" {
+				c.Replace(NewLiteral(tokens.Position{}, "Z"))
+			}
+			return true
+		}, expect: "Z b {Z | C}"},
+		// deleting the only element of an option removes the option
+		{id: 2, input: "A [B] C", pre: func(c *Cursor) bool {
+			if lit, ok := c.Node().(*Literal); ok && lit.String() == "B" {
+				c.Delete()
+			}
+			return true
+		}, expect: "A C"},
+		// deleting all but one element collapses the sequence
+		{id: 3, input: "A | B C", pre: func(c *Cursor) bool {
+			if lit, ok := c.Node().(*Literal); ok && lit.String() == "C" {
+				c.Delete()
+			}
+			return true
+		}, expect: "A | B"},
+		// deleting everything leaves nothing
+		{id: 4, input: "{A} | [B]", pre: func(c *Cursor) bool {
+			if _, ok := c.Node().(*Literal); ok {
+				c.Delete()
+			}
+			return true
+		}, expect: ""},
+		// inject a trace before every reference
+		{id: 5, input: "a (b | C)", post: func(c *Cursor) bool {
+			if name, ok := c.Node().(*Name); ok {
+				c.Replace(Sequence{NewLiteral(tokens.Position{}, "Trace"), name})
+			}
+			return true
+		}, expect: "Trace a (Trace b | C)"},
+		// insert alternatives
+		{id: 6, input: "A | B", pre: func(c *Cursor) bool {
+			if lit, ok := c.Node().(*Literal); ok && c.Index() == 0 {
+				c.InsertBefore(NewLiteral(tokens.Position{}, "Y"))
+				c.InsertAfter(NewLiteral(tokens.Position{}, lit.String()+"1"))
+				c.InsertAfter(NewLiteral(tokens.Position{}, lit.String()+"2"))
+			}
+			return true
+		}, expect: "Y | A | A1 | A2 | B"},
+		// pre returning false skips the children
+		{id: 7, input: "A {A}", pre: func(c *Cursor) bool {
+			if lit, ok := c.Node().(*Literal); ok {
+				c.Replace(NewLiteral(tokens.Position{}, lit.String()+"1"))
+			}
+			_, ok := c.Node().(*Repetition)
+			return !ok
+		}, expect: "A1 {A}"},
+		// post returning false stops the traversal
+		{id: 8, input: "A B C", post: func(c *Cursor) bool {
+			if lit, ok := c.Node().(*Literal); ok {
+				c.Delete()
+				return lit.String() != "B"
+			}
+			return true
+		}, expect: "C"},
+	} {
+		grammar, errs := Parse([]byte("x = " + tc.input + " ."))
+		if errs != nil {
+			t.Fatalf("%d: Parse failed: %v", tc.id, errs)
+		}
+		before := Clone(grammar)
+		got := Apply(grammar["x"].Expr, tc.pre, tc.post)
+		if s := ExprString(got); s != tc.expect {
+			t.Errorf("%d: want %q, got %q", tc.id, tc.expect, s)
+		}
+		if !reflect.DeepEqual(before, grammar) {
+			t.Errorf("%d: Apply modified its input", tc.id)
+		}
+	}
+}
+
+func TestApplyNormalize(t *testing.T) {
+	z := func() Expression { return NewLiteral(tokens.Position{Line: 9, Col: 9}, "Z") }
+	for _, tc := range []struct {
+		id     int
+		pre    ApplyFunc
+		post   ApplyFunc
+		expect string
+		pos    tokens.Position
+	}{
+		// an empty replacement deletes the node
+		{id: 1, pre: func(c *Cursor) bool {
+			if _, ok := c.Node().(Sequence); ok {
+				c.Replace(Sequence{})
+			}
+			return true
+		}, expect: "D", pos: tokens.Position{Line: 1, Col: 11}},
+		{id: 2, post: func(c *Cursor) bool {
+			if _, ok := c.Node().(Sequence); ok {
+				c.Replace(Alternative{Sequence{}, Sequence{}})
+			}
+			return true
+		}, expect: "D", pos: tokens.Position{Line: 1, Col: 11}},
+		// a replacement with a single element is replaced by the element
+		{id: 3, post: func(c *Cursor) bool {
+			if _, ok := c.Node().(Alternative); ok {
+				c.Replace(Sequence{Alternative{z()}})
+			}
+			return true
+		}, expect: "Z", pos: tokens.Position{Line: 9, Col: 9}},
+		// inserted nodes are normalized too
+		{id: 4, pre: func(c *Cursor) bool {
+			if _, ok := c.Node().(Sequence); ok {
+				c.InsertBefore(Sequence{})
+				c.InsertAfter(Sequence{Sequence{z()}})
+				c.Delete()
+			}
+			return true
+		}, expect: "Z | D", pos: tokens.Position{Line: 9, Col: 9}},
+	} {
+		grammar, errs := Parse([]byte("x = B C | D ."))
+		if errs != nil {
+			t.Fatalf("%d: Parse failed: %v", tc.id, errs)
+		}
+		got := Apply(grammar["x"].Expr, tc.pre, tc.post)
+		if s := ExprString(got); s != tc.expect {
+			t.Errorf("%d: want %q, got %q", tc.id, tc.expect, s)
+		} else if pos := Position(got); pos != tc.pos || got.Pos() != tc.pos.Line {
+			t.Errorf("%d: want position %v, got %v", tc.id, tc.pos, pos)
+		}
+	}
+
+	for _, op := range []string{"Replace", "InsertBefore", "InsertAfter"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s(nil): want panic", op)
+				}
+			}()
+			grammar, _ := Parse([]byte("x = B C ."))
+			Apply(grammar["x"].Expr, func(c *Cursor) bool {
+				if c.Index() == 0 {
+					switch op {
+					case "Replace":
+						c.Replace(nil)
+					case "InsertBefore":
+						c.InsertBefore(nil)
+					case "InsertAfter":
+						c.InsertAfter(nil)
+					}
+				}
+				return true
+			}, nil)
+		}()
+	}
+}
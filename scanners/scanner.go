@@ -12,11 +12,25 @@ import (
 	"unicode/utf8"
 )
 
+// A Mode controls how the scanner treats the input.
+type Mode uint
+
+const (
+	ScanComments Mode = 1 << iota // return comments as COMMENT tokens
+	KeepTrivia                    // record the text around each token so the input can be reproduced
+)
+
 // Scan returns a slice containing all the tokens in the input.
 // It always adds an end of input token to that slice.
 func Scan(input []byte) []*tokens.Token {
+	return ScanMode(input, 0)
+}
+
+// ScanMode is like Scan but the mode controls how the input is scanned.
+func ScanMode(input []byte, mode Mode) []*tokens.Token {
 	pos := tokens.Position{Line: 1, Col: 1}
 	s := &scanner{
+		mode:   mode,
 		line:   pos.Line,
 		col:    pos.Col,
 		buffer: input,
@@ -28,13 +42,15 @@ func Scan(input []byte) []*tokens.Token {
 		toks = append(toks, token)
 		pos = token.Pos
 	}
-	return append(toks, &tokens.Token{Pos: pos, Kind: tokens.EOF})
+	return append(toks, &tokens.Token{Pos: pos, Kind: tokens.EOF, Leading: s.leading})
 }
 
 type scanner struct {
+	mode      Mode
 	line, col int
 	buffer    []byte
 	delims    []byte
+	leading   []byte // trivia before the end of input
 }
 
 func (s *scanner) getch() rune {
@@ -57,10 +73,14 @@ func (s *scanner) iseof() bool {
 // next returns the next token from the input, skipping spaces, comments, and invalid runes.
 // returns nil only if the input is empty.
 func (s *scanner) next() *tokens.Token {
+	leading := s.buffer
+
 	// skip spaces, invalid runes, and comments
 	for !s.iseof() {
 		r := s.peekch()
-		if r == ';' {
+		if r == ';' && s.mode&ScanComments != 0 {
+			break
+		} else if r == ';' {
 			if eol := bytes.IndexByte(s.buffer, '\n'); eol == -1 {
 				s.buffer = nil
 			} else {
@@ -73,7 +93,12 @@ func (s *scanner) next() *tokens.Token {
 		}
 	}
 
+	leading = leading[:len(leading)-len(s.buffer)]
+
 	if s.iseof() {
+		if s.mode&KeepTrivia != 0 {
+			s.leading = leading
+		}
 		return nil
 	}
 
@@ -82,6 +107,12 @@ func (s *scanner) next() *tokens.Token {
 	r := s.getch()
 
 	switch r {
+	case ';': // only when scanning comments; the comment does not include the end of line
+		tok.Kind = tokens.COMMENT
+		for !s.iseof() && s.buffer[0] != '\n' {
+			s.getch()
+		}
+		tok.Text = start[:len(start)-len(s.buffer)]
 	case '=':
 		tok.Kind = tokens.EQ
 	case ')':
@@ -121,9 +152,38 @@ func (s *scanner) next() *tokens.Token {
 		tok.Text = start[:len(start)-len(s.buffer)]
 	}
 
+	if s.mode&KeepTrivia != 0 {
+		tok.Text = start[:len(start)-len(s.buffer)]
+		tok.Leading, tok.Trailing = leading, s.trailing()
+	}
+
 	return tok
 }
 
+// trailing skips spaces, invalid runes, and comments up to and including
+// the end of the line. It returns the text that was skipped.
+func (s *scanner) trailing() []byte {
+	start := s.buffer
+	for !s.iseof() {
+		r := s.peekch()
+		if r == '\n' {
+			s.getch()
+			break
+		} else if r == ';' && s.mode&ScanComments == 0 {
+			if eol := bytes.IndexByte(s.buffer, '\n'); eol == -1 {
+				s.buffer = nil
+			} else {
+				s.buffer = s.buffer[eol:]
+			}
+		} else if r == utf8.RuneError || unicode.IsSpace(r) {
+			s.getch()
+		} else {
+			break
+		}
+	}
+	return start[:len(start)-len(s.buffer)]
+}
+
 func (s *scanner) peekch() rune {
 	if s.iseof() {
 		return utf8.RuneError
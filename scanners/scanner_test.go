@@ -14,6 +14,7 @@ func TestScanner(t *testing.T) {
 	for _, tc := range []struct {
 		id     int
 		dump   bool // if true, log all tokens
+		mode   scanners.Mode
 		input  string
 		expect []tokens.Kind
 	}{
@@ -47,8 +48,15 @@ func TestScanner(t *testing.T) {
 			tokens.NONTERMINAL,
 			tokens.EOF,
 		}},
+		{id: 5, mode: scanners.ScanComments, input: "; comment\n a ; trailing\n;", expect: []tokens.Kind{
+			tokens.COMMENT,
+			tokens.NONTERMINAL,
+			tokens.COMMENT,
+			tokens.COMMENT,
+			tokens.EOF,
+		}},
 	} {
-		toks := scanners.Scan([]byte(tc.input))
+		toks := scanners.ScanMode([]byte(tc.input), tc.mode)
 		if tc.dump {
 			for _, token := range toks {
 				t.Logf("%d: %d:%d: %s\n", tc.id, token.Line(), token.Column(), token.String())
@@ -82,3 +90,27 @@ func TestScanner(t *testing.T) {
 		}
 	}
 }
+
+func TestScanTrivia(t *testing.T) {
+	for _, tc := range []struct {
+		id    int
+		mode  scanners.Mode
+		input string
+	}{
+		{id: 1, input: ""},
+		{id: 2, input: "a6 = B_5 . | ( ) [ ] { } ; comments"},
+		{id: 3, input: "; comment\n\n a = b ; trailing\n\t| C . \n\n; end\n\n"},
+		{id: 4, input: "b@t\na\nJab+Ba;\nb\xff\xfe."},
+		{id: 5, mode: scanners.ScanComments, input: "; comment\n a = b ; trailing\n\t| C .\n; end"},
+	} {
+		var got []byte
+		for _, token := range scanners.ScanMode([]byte(tc.input), tc.mode|scanners.KeepTrivia) {
+			got = append(got, token.Leading...)
+			got = append(got, token.Text...)
+			got = append(got, token.Trailing...)
+		}
+		if string(got) != tc.input {
+			t.Errorf("%d: want %q, got %q\n", tc.id, tc.input, string(got))
+		}
+	}
+}
@@ -17,6 +17,10 @@ type Token struct {
 	Pos  Position
 	Kind Kind
 	Text []byte
+	// Leading and Trailing are the spaces, comments, and invalid runes
+	// around the token. They are set only when the scanner keeps trivia.
+	// Trailing runs up to and including the end of the line.
+	Leading, Trailing []byte
 }
 
 func (t *Token) Column() int {
@@ -59,6 +63,8 @@ func (t *Token) String() string {
 		return fmt.Sprintf("(%d '{')", t.Line())
 	case END_REPETITION:
 		return fmt.Sprintf("(%d '}')", t.Line())
+	case COMMENT:
+		return fmt.Sprintf("(%d %q)", t.Line(), string(t.Text))
 	case EOF:
 		return fmt.Sprintf("(%d $)", t.Line())
 	}
@@ -93,6 +99,8 @@ func (k Kind) String() string {
 		return "START_REPETITION"
 	case END_REPETITION:
 		return "END_REPETITION"
+	case COMMENT:
+		return "COMMENT"
 	case EOF:
 		return "EOF"
 	}
@@ -112,5 +120,6 @@ const (
 	END_OPTION
 	START_REPETITION
 	END_REPETITION
+	COMMENT
 	EOF
 )
@@ -0,0 +1,204 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package transform
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/tokens"
+	"strings"
+)
+
+// An Unfactored is a common prefix that LeftFactor could not factor out
+// because it only appears after inlining other productions.
+type Unfactored struct {
+	Production string
+	Pos        tokens.Position // position of the Alternative
+	// Alternatives are the indexes of the two alternatives in the Alternative.
+	Alternatives [2]int
+	Prefix       string   // the terminal or nonterminal both alternatives start with
+	Inline       []string // the productions that would have to be inlined
+}
+
+func (u Unfactored) String() string {
+	return fmt.Sprintf("%d: %s: alternatives %d and %d both start with %s after inlining %s",
+		u.Pos.Line, u.Production, u.Alternatives[0]+1, u.Alternatives[1]+1, u.Prefix, strings.Join(u.Inline, ", "))
+}
+
+// LeftFactor returns a copy of the grammar with every Alternative left
+// factored by FactorAlternative, and the common prefixes that could not
+// be factored because they only appear after inlining other productions.
+// Only the leading element of each alternative is followed into the
+// productions it references.
+func LeftFactor(grammar ebnf.Grammar) (ebnf.Grammar, []Unfactored) {
+	result := ebnf.Clone(grammar)
+	var list []Unfactored
+	for _, prod := range ebnf.Productions(result) {
+		prod.Expr = ebnf.Apply(prod.Expr, nil, func(c *ebnf.Cursor) bool {
+			if x, ok := c.Node().(ebnf.Alternative); ok {
+				c.Replace(FactorAlternative(x))
+			}
+			return true
+		})
+		ebnf.Apply(prod.Expr, func(c *ebnf.Cursor) bool {
+			if x, ok := c.Node().(ebnf.Alternative); ok {
+				list = append(list, unfactored(result, prod, x)...)
+			}
+			return true
+		}, nil)
+	}
+	return result, list
+}
+
+// FactorAlternative returns the alternatives with their common leading
+// elements pulled out, repeating until no two alternatives start with
+// the same element. "a b | a c | d" becomes "a (b | c) | d" and
+// "a b | a b c" becomes "a b [c]". Elements are compared with
+// ebnf.EqualExpr. The alternatives are kept in order, with each factored
+// alternative taking the place of the first alternative it came from.
+func FactorAlternative(x ebnf.Alternative) ebnf.Expression {
+	return factor(ebnf.Alternatives(x))
+}
+
+func factor(alts [][]ebnf.Expression) ebnf.Expression {
+	var list [][]ebnf.Expression
+	used := make([]bool, len(alts))
+	for i, alt := range alts {
+		if used[i] {
+			continue
+		}
+		group := []int{i}
+		for j := i + 1; len(alt) != 0 && j < len(alts); j++ {
+			if !used[j] && len(alts[j]) != 0 && ebnf.EqualExpr(alts[j][0], alt[0]) {
+				group, used[j] = append(group, j), true
+			}
+		}
+		if len(group) == 1 {
+			list = append(list, alt)
+			continue
+		}
+
+		// find the longest prefix shared by the group
+		n := 1
+		for ; n < len(alt); n++ {
+			shared := true
+			for _, j := range group[1:] {
+				if n >= len(alts[j]) || !ebnf.EqualExpr(alts[j][n], alt[n]) {
+					shared = false
+					break
+				}
+			}
+			if !shared {
+				break
+			}
+		}
+		var suffixes [][]ebnf.Expression
+		empty := false
+		for _, j := range group {
+			if suffix := alts[j][n:]; len(suffix) == 0 {
+				empty = true
+			} else {
+				suffixes = append(suffixes, suffix)
+			}
+		}
+		factored := append([]ebnf.Expression{}, alt[:n]...)
+		if len(suffixes) != 0 {
+			rest := factor(suffixes)
+			pos := ebnf.Position(suffixes[0][0])
+			if empty {
+				factored = append(factored, ebnf.NewOption(pos, rest))
+			} else if _, ok := rest.(ebnf.Alternative); ok {
+				factored = append(factored, ebnf.NewGroup(pos, rest))
+			} else {
+				factored = append(factored, ebnf.Elements(rest)...)
+			}
+		}
+		list = append(list, factored)
+	}
+	var result ebnf.Alternative
+	for _, alt := range list {
+		result = append(result, sequence(alt))
+	}
+	if len(result) == 1 {
+		return result[0]
+	}
+	return result
+}
+
+// unfactored returns the pairs of alternatives that start with different
+// elements but with the same terminal or nonterminal after inlining.
+func unfactored(grammar ebnf.Grammar, prod *ebnf.Production, x ebnf.Alternative) []Unfactored {
+	alts := ebnf.Alternatives(x)
+	corners := make([]map[string][]string, len(alts))
+	for i, alt := range alts {
+		if len(alt) != 0 {
+			corners[i] = leftCorners(grammar, prod.Name.String(), alt[0])
+		}
+	}
+	var list []Unfactored
+	for i := range alts {
+		for j := i + 1; j < len(alts); j++ {
+			// pick the shared symbol that needs the fewest productions inlined
+			best, inline := "", []string(nil)
+			for sym, a := range corners[i] {
+				b, ok := corners[j][sym]
+				if !ok {
+					continue
+				}
+				path := append(append([]string{}, a...), b...)
+				if best == "" || len(path) < len(inline) || (len(path) == len(inline) && sym < best) {
+					best, inline = sym, path
+				}
+			}
+			if best != "" {
+				list = append(list, Unfactored{
+					Production:   prod.Name.String(),
+					Pos:          ebnf.Position(x),
+					Alternatives: [2]int{i, j},
+					Prefix:       best,
+					Inline:       inline,
+				})
+			}
+		}
+	}
+	return list
+}
+
+// leftCorners returns the terminals and nonterminals that can start the
+// element, each with the productions that are inlined to reach it.
+// Only the leading element of each alternative is followed. Paths through
+// the production itself are left recursion, not common prefixes, and are
+// not followed.
+func leftCorners(grammar ebnf.Grammar, self string, x ebnf.Expression) map[string][]string {
+	corners := make(map[string][]string)
+	type item struct {
+		x    ebnf.Expression
+		path []string
+	}
+	// breadth first, so each path is as short as possible
+	for queue := []item{{x: x}}; len(queue) != 0; queue = queue[1:] {
+		switch x := queue[0].x.(type) {
+		case *ebnf.Name:
+			name := x.String()
+			if _, ok := corners[name]; ok || name == self {
+				continue
+			}
+			corners[name] = queue[0].path
+			if prod, ok := grammar[name]; ok {
+				path := append(queue[0].path[:len(queue[0].path):len(queue[0].path)], name)
+				for _, alt := range ebnf.Alternatives(prod.Expr) {
+					if len(alt) != 0 {
+						queue = append(queue, item{x: alt[0], path: path})
+					}
+				}
+			}
+		case *ebnf.Literal:
+			if _, ok := corners[x.String()]; !ok {
+				corners[x.String()] = queue[0].path
+			}
+		}
+	}
+	return corners
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package transform
+
+import (
+	"github.com/mdhender/ebnf"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFactorAlternative(t *testing.T) {
+	for i, tc := range []struct {
+		input string
+		want  string
+	}{
+		{`s = A B | A C | D .`, "A (B | C) | D"},
+		{`s = A B | A B C .`, "A B [C]"},
+		{`s = A B C | D | A B E | A F .`, "A (B (C | E) | F) | D"},
+		{`s = (A | B) C | (A | B) D .`, "(A | B) (C | D)"},
+		{`s = [A] B | [A] C | A .`, "[A] (B | C) | A"},
+		{`s = A | A .`, "A"},
+		{`s = A | B .`, "A | B"},
+	} {
+		grammar := parse(t, tc.input)
+		before := ebnf.ExprString(grammar["s"].Expr)
+		if got := ebnf.ExprString(FactorAlternative(grammar["s"].Expr.(ebnf.Alternative))); got != tc.want {
+			t.Errorf("%d: want %q, got %q", i+1, tc.want, got)
+		}
+		if ebnf.ExprString(grammar["s"].Expr) != before {
+			t.Errorf("%d: input was modified", i+1)
+		}
+	}
+}
+
+func TestLeftFactor(t *testing.T) {
+	grammar := parse(t, `
+		s = x | y | { A B | A C } D .
+		x = t A .
+		y = t B .
+		t = T .`)
+	result, unfactored := LeftFactor(grammar)
+	if got, want := ebnf.ExprString(result["s"].Expr), "x | y | {A (B | C)} D"; got != want {
+		t.Errorf("s: want %q, got %q", want, got)
+	}
+	var got []string
+	for _, u := range unfactored {
+		got = append(got, u.String())
+	}
+	want := []string{"2: s: alternatives 1 and 2 both start with t after inlining x, y"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestLeftFactorLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar := parse(t, string(input))
+	result, unfactored := LeftFactor(grammar)
+	for name, want := range map[string]string{
+		"functioncall": "prefixexp (args | Colon Name args)",
+		"var":          "Name | prefixexp (LBracket exp RBracket | Dot Name)",
+	} {
+		if got := ebnf.ExprString(result[name].Expr); got != want {
+			t.Errorf("%s: want %q, got %q", name, want, got)
+		}
+	}
+	if errs := ebnf.Verify(result, "chunk"); errs != nil {
+		t.Errorf("Verify failed: %v", errs)
+	}
+	sameLanguage(t, grammar, result, "chunk")
+	found := false
+	for _, u := range unfactored {
+		if u.Production == "stat" && u.Prefix == "functioncall" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("stat: want varlist and functioncall reported, got %v", unfactored)
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+// Package transform rewrites EBNF grammars into equivalent grammars
+// that are easier to parse, for example by removing left recursion.
+//
+// Transformations never modify their input. The grammars they return
+// keep the names, positions, and comments of the original productions
+// and describe the same language.
+package transform
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"github.com/mdhender/ebnf/tokens"
+	"sort"
+)
+
+// An Origin records where a rewritten production came from.
+type Origin struct {
+	Name     string           // name of the production
+	Original *ebnf.Production // the production before it was rewritten
+	// Substituted are the productions whose alternatives were copied
+	// into the production, in the order they were substituted.
+	Substituted []string
+}
+
+func (o Origin) String() string {
+	if len(o.Substituted) == 0 {
+		return fmt.Sprintf("%d: %s: rewritten", o.Original.Pos(), o.Name)
+	}
+	return fmt.Sprintf("%d: %s: rewritten after substituting %v", o.Original.Pos(), o.Name, o.Substituted)
+}
+
+// EliminateLeftRecursion returns a copy of the grammar with direct and
+// indirect left recursion replaced by repetitions, along with the origin
+// of every production that was rewritten.
+//
+// A production "a = a x | a y | b | c ." becomes "a = (b | c) {x | y} .".
+// Productions that are left recursive through other productions are
+// handled by substituting the alternatives of the productions that come
+// earlier in the source, then removing the direct recursion that results.
+// Leading groups, options, and repetitions are expanded as needed to
+// expose the recursion.
+//
+// It returns errors if the recursion is hidden behind a nullable
+// reference to another production, or if a left recursive production
+// has no alternative that ends the recursion.
+func EliminateLeftRecursion(grammar ebnf.Grammar) (ebnf.Grammar, []Origin, []error) {
+	sets, err := analysis.Analyze(grammar)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	c := sets.Compiled()
+	result := ebnf.Clone(grammar)
+	t := &leftrec{sets: sets, grammar: result}
+
+	var origins []Origin
+	var errs []error
+	for _, group := range recursiveGroups(sets) {
+		// members are processed in source order
+		t.targets = make(map[string]bool)
+		for _, sym := range group {
+			name := c.NonTerminal(sym)
+			prod := result[name]
+			t.targets[name] = true
+			alts, substituted, changed, err := t.rewrite(prod)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			} else if !changed {
+				continue
+			}
+			expr, err := t.eliminate(prod, alts)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			original := grammar[name]
+			prod.Expr = expr
+			origins = append(origins, Origin{Name: name, Original: original, Substituted: substituted})
+		}
+	}
+	if errs != nil {
+		return nil, nil, errs
+	}
+	return result, origins, nil
+}
+
+// recursiveGroups returns the sets of productions that are left recursive
+// through each other, each in source order. The groups are in the order
+// of their first production.
+func recursiveGroups(sets *analysis.Sets) [][]ebnf.Symbol {
+	// every edge of a left recursive cycle is on one of the cycles found,
+	// so joining the cycles that share a production gives the groups.
+	group := make(map[ebnf.Symbol]int)
+	var groups [][]ebnf.Symbol
+	for _, cycle := range analysis.LeftRecursion(sets) {
+		at := -1
+		for _, step := range cycle.Steps {
+			if g, ok := group[step.From]; ok && at == -1 {
+				at = g
+			} else if ok && g != at {
+				// merge group g into at
+				for _, sym := range groups[g] {
+					group[sym] = at
+				}
+				groups[at], groups[g] = append(groups[at], groups[g]...), nil
+			}
+		}
+		if at == -1 {
+			at, groups = len(groups), append(groups, nil)
+		}
+		for _, step := range cycle.Steps {
+			if g, ok := group[step.From]; !ok || g != at {
+				group[step.From] = at
+				groups[at] = append(groups[at], step.From)
+			}
+		}
+	}
+	var list [][]ebnf.Symbol
+	for _, g := range groups {
+		if g != nil {
+			sort.Slice(g, func(i, j int) bool { return g[i] < g[j] })
+			list = append(list, g)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i][0] < list[j][0] })
+	return list
+}
+
+type leftrec struct {
+	sets    *analysis.Sets
+	grammar ebnf.Grammar
+	targets map[string]bool // members of the group processed so far, including the current one
+}
+
+// rewrite returns the alternatives of the production, each as a list of
+// elements, with leading references to earlier members of the group
+// replaced by their alternatives and leading groups, options, and
+// repetitions expanded wherever they hide a reference to a target.
+// It returns false if nothing had to be changed.
+func (t *leftrec) rewrite(prod *ebnf.Production) (alts [][]ebnf.Expression, substituted []string, changed bool, err error) {
+	self := prod.Name.String()
+	work := ebnf.Alternatives(prod.Expr)
+	for len(work) != 0 {
+		alt := work[0]
+		work = work[1:]
+		list, expanded, err := t.split(prod, alt)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		changed = changed || expanded
+		for _, alt := range list {
+			if len(alt) == 0 {
+				alts = append(alts, alt)
+				continue
+			}
+			name, ok := alt[0].(*ebnf.Name)
+			if !ok || name.String() == self || !t.targets[name.String()] {
+				if ok && name.String() == self {
+					changed = true
+				}
+				alts = append(alts, alt)
+				continue
+			}
+			// an earlier member of the group; substitute its alternatives
+			// and keep the alternatives in order
+			changed, substituted = true, appendOnce(substituted, name.String())
+			var list [][]ebnf.Expression
+			for _, x := range ebnf.Alternatives(t.grammar[name.String()].Expr) {
+				list = append(list, append(cloneAll(x), cloneAll(alt[1:])...))
+			}
+			work = append(list, work...)
+		}
+	}
+	return alts, substituted, changed, nil
+}
+
+// split expands the leading groups, options, and repetitions of the
+// alternative until every alternative that can start with a target
+// starts with a reference to it. It returns false if nothing was expanded.
+func (t *leftrec) split(prod *ebnf.Production, alt []ebnf.Expression) ([][]ebnf.Expression, bool, error) {
+	if !t.leadsToTarget(alt) {
+		return [][]ebnf.Expression{alt}, false, nil
+	}
+	head, rest := alt[0], alt[1:]
+	var list [][]ebnf.Expression
+	var expand []ebnf.Expression
+	switch x := head.(type) {
+	case *ebnf.Name:
+		if t.targets[x.String()] {
+			return [][]ebnf.Expression{alt}, false, nil
+		}
+		return nil, false, fmt.Errorf("%d: %s: left recursion through nullable %s is not supported", x.Pos(), prod.Name, x)
+	case *ebnf.Group, ebnf.Alternative:
+		for _, a := range ebnf.Alternatives(x) {
+			expand = append(expand, ebnf.Sequence(append(a, cloneAll(rest)...)))
+		}
+	case *ebnf.Option:
+		for _, a := range ebnf.Alternatives(x.Body) {
+			expand = append(expand, ebnf.Sequence(append(a, cloneAll(rest)...)))
+		}
+		expand = append(expand, ebnf.Sequence(cloneAll(rest)))
+	case *ebnf.Repetition:
+		if t.nullable(x.Body) {
+			return nil, false, fmt.Errorf("%d: %s: left recursion through a repetition that can be empty is not supported", x.Pos(), prod.Name)
+		}
+		// {x} rest is x {x} rest | rest, with a copy of {x} and rest in each
+		for _, a := range ebnf.Alternatives(x.Body) {
+			expand = append(expand, ebnf.Sequence(append(append(a, ebnf.CloneExpr(x)), cloneAll(rest)...)))
+		}
+		expand = append(expand, ebnf.Sequence(cloneAll(rest)))
+	default:
+		panic(fmt.Sprintf("internal error: unexpected type %T", x))
+	}
+	for _, e := range expand {
+		more, _, err := t.split(prod, ebnf.Elements(e))
+		if err != nil {
+			return nil, false, err
+		}
+		list = append(list, more...)
+	}
+	return list, true, nil
+}
+
+// eliminate returns the expression for the alternatives with the direct
+// left recursion replaced by a repetition.
+func (t *leftrec) eliminate(prod *ebnf.Production, alts [][]ebnf.Expression) (ebnf.Expression, error) {
+	self := prod.Name.String()
+	var base, tails [][]ebnf.Expression
+	empty := false
+	for _, alt := range alts {
+		if len(alt) == 0 {
+			empty = true
+		} else if name, ok := alt[0].(*ebnf.Name); ok && name.String() == self {
+			if len(alt) > 1 {
+				tails = append(tails, alt[1:])
+			}
+		} else {
+			base = append(base, alt)
+		}
+	}
+	if len(tails) == 0 {
+		if len(base) == 0 {
+			return nil, nil
+		} else if empty {
+			return ebnf.NewOption(ebnf.Position(base[0][0]), choice(ebnf.Position(base[0][0]), base, false)), nil
+		}
+		return choice(ebnf.Position(base[0][0]), base, false), nil
+	} else if len(base) == 0 && !empty {
+		return nil, fmt.Errorf("%d: %s: left recursion has no alternative to end it", prod.Pos(), prod.Name)
+	}
+	pos := ebnf.Position(tails[0][0])
+	rep := ebnf.NewRepetition(pos, choice(pos, tails, false))
+	if len(base) == 0 {
+		return rep, nil
+	}
+	head := choice(ebnf.Position(base[0][0]), base, true)
+	if empty {
+		head = ebnf.NewOption(ebnf.Position(head), head)
+	}
+	return ebnf.Sequence(append(ebnf.Elements(head), rep)), nil
+}
+
+// choice returns the non-empty alternatives as a single expression.
+// If grouped is set, the result can be used as an element of a sequence.
+func choice(pos tokens.Position, alts [][]ebnf.Expression, grouped bool) ebnf.Expression {
+	var list ebnf.Alternative
+	for _, alt := range alts {
+		list = append(list, sequence(alt))
+	}
+	if len(list) == 1 {
+		return list[0]
+	} else if grouped {
+		return ebnf.NewGroup(pos, list)
+	}
+	return list
+}
+
+// sequence returns the elements as a single expression.
+func sequence(list []ebnf.Expression) ebnf.Expression {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return ebnf.Sequence(list)
+}
+
+// leadsToTarget returns true if the elements can start with a reference to a target.
+func (t *leftrec) leadsToTarget(list []ebnf.Expression) bool {
+	for _, e := range list {
+		if t.startsWithTarget(e) {
+			return true
+		} else if !t.nullable(e) {
+			return false
+		}
+	}
+	return false
+}
+
+func (t *leftrec) startsWithTarget(x ebnf.Expression) bool {
+	switch x := x.(type) {
+	case *ebnf.Name:
+		return t.targets[x.String()]
+	case ebnf.Alternative:
+		for _, e := range x {
+			if t.startsWithTarget(e) {
+				return true
+			}
+		}
+	case ebnf.Sequence:
+		return t.leadsToTarget(x)
+	case *ebnf.Group:
+		return t.startsWithTarget(x.Body)
+	case *ebnf.Option:
+		return t.startsWithTarget(x.Body)
+	case *ebnf.Repetition:
+		return t.startsWithTarget(x.Body)
+	}
+	return false
+}
+
+// nullable returns true if the expression can derive the empty string.
+func (t *leftrec) nullable(x ebnf.Expression) bool {
+	switch x := x.(type) {
+	case nil:
+		return true
+	case *ebnf.Name:
+		sym, ok := t.sets.Compiled().NonTerminalID(x.String())
+		return ok && t.sets.Nullable(sym)
+	case ebnf.Alternative:
+		for _, e := range x {
+			if t.nullable(e) {
+				return true
+			}
+		}
+	case ebnf.Sequence:
+		for _, e := range x {
+			if !t.nullable(e) {
+				return false
+			}
+		}
+		return true
+	case *ebnf.Group:
+		return t.nullable(x.Body)
+	case *ebnf.Option, *ebnf.Repetition:
+		return true
+	}
+	return false
+}
+
+func cloneAll(list []ebnf.Expression) []ebnf.Expression {
+	clone := make([]ebnf.Expression, len(list))
+	for i, e := range list {
+		clone[i] = ebnf.CloneExpr(e)
+	}
+	return clone
+}
+
+func appendOnce(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
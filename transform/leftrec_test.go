@@ -0,0 +1,178 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package transform
+
+import (
+	"bytes"
+	"github.com/mdhender/ebnf"
+	"github.com/mdhender/ebnf/analysis"
+	"os"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src string) ebnf.Grammar {
+	t.Helper()
+	grammar, errs := ebnf.Parse([]byte(src))
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	return grammar
+}
+
+// sameLanguage checks that the grammars derive the same sentences from
+// the start production, up to a length that keeps the test fast.
+func sameLanguage(t *testing.T, a, b ebnf.Grammar, start string) {
+	t.Helper()
+	var bnf [2]*analysis.BNF
+	for i, grammar := range []ebnf.Grammar{a, b} {
+		c, err := ebnf.Compile(grammar)
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		bnf[i] = analysis.NewBNF(c)
+	}
+	d, err := analysis.FindDifference(bnf[0], bnf[1], analysis.EquivalenceOptions{MaxLength: 6, Starts: [2]string{start, start}})
+	if err != nil {
+		t.Fatalf("FindDifference failed: %v", err)
+	} else if d != nil {
+		t.Errorf("language changed: %v", d)
+	}
+}
+
+func format(t *testing.T, grammar ebnf.Grammar) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ebnf.Fprint(&buf, grammar); err != nil {
+		t.Fatalf("Fprint failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestEliminateLeftRecursion(t *testing.T) {
+	for i, tc := range []struct {
+		input   string
+		want    string
+		origins []string
+	}{
+		{`s = A s | B .`, "s = A s\n  | B .\n", nil},
+		{`exp = exp binop exp | unop exp .`,
+			"exp = unop exp {binop exp} .\n",
+			[]string{"1: exp: rewritten"}},
+		{`a = a X | a Y | B | C .`,
+			"a = (B | C) {X | Y} .\n",
+			[]string{"1: a: rewritten"}},
+		{`s = [ A ] s B | C .`,
+			"s = (A s B | C) {B} .\n",
+			[]string{"1: s: rewritten"}},
+		{`s = { s A } B .`,
+			"s = B {A {s A} B} .\n",
+			[]string{"1: s: rewritten"}},
+		{`s = [ s A | B ] .`,
+			"s = [B] {A} .\n",
+			[]string{"1: s: rewritten"}},
+		{`s = t A | B .
+		  t = s C | D .`,
+			"s = t A\n  | B .\nt = (B C | D) {A C} .\n",
+			[]string{"2: t: rewritten after substituting [s]"}},
+	} {
+		grammar := parse(t, tc.input)
+		before := format(t, grammar)
+		result, origins, errs := EliminateLeftRecursion(grammar)
+		if errs != nil {
+			t.Errorf("%d: EliminateLeftRecursion failed: %v", i+1, errs)
+			continue
+		}
+		if got := format(t, result); got != tc.want {
+			t.Errorf("%d: want\n%s\ngot\n%s", i+1, tc.want, got)
+		}
+		var got []string
+		for _, o := range origins {
+			got = append(got, o.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.origins, "\n") {
+			t.Errorf("%d: origins: want %q, got %q", i+1, tc.origins, got)
+		}
+		if format(t, grammar) != before {
+			t.Errorf("%d: input was modified", i+1)
+		}
+		if x := shared(result); x != nil {
+			t.Errorf("%d: %s appears more than once in the result", i+1, ebnf.ExprString(x))
+		}
+	}
+
+	for i, tc := range []struct {
+		input string
+		want  string
+	}{
+		{`s = n s B | C .
+		  n = [ A ] .`, "1: s: left recursion through nullable n is not supported"},
+		{`s = s A .`, "1: s: left recursion has no alternative to end it"},
+	} {
+		_, _, errs := EliminateLeftRecursion(parse(t, tc.input))
+		if len(errs) != 1 || errs[0].Error() != tc.want {
+			t.Errorf("%d: want %q, got %v", i+1, tc.want, errs)
+		}
+	}
+}
+
+func TestEliminateLeftRecursionLua(t *testing.T) {
+	input, err := os.ReadFile("../testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar := parse(t, string(input))
+	result, origins, errs := EliminateLeftRecursion(grammar)
+	if errs != nil {
+		t.Fatalf("EliminateLeftRecursion failed: %v", errs)
+	}
+	if len(result) != len(grammar) {
+		t.Errorf("want %d productions, got %d", len(grammar), len(result))
+	}
+	var names []string
+	for _, o := range origins {
+		names = append(names, o.Name)
+	}
+	if got, want := strings.Join(names, " "), "prefixexp functioncall exp"; got != want {
+		t.Errorf("origins: want %q, got %q", want, got)
+	}
+	if errs := ebnf.Verify(result, "chunk"); errs != nil {
+		t.Errorf("Verify failed: %v", errs)
+	}
+	if x := shared(result); x != nil {
+		t.Errorf("%s appears more than once in the result", ebnf.ExprString(x))
+	}
+	sameLanguage(t, grammar, result, "chunk")
+	sets, err := analysis.Analyze(result, "chunk")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if cycles := analysis.LeftRecursion(sets); cycles != nil {
+		t.Errorf("want no left recursion, got %v", cycles)
+	}
+	if got, want := ebnf.ExprString(result["prefixexp"].Expr), "(Name | functioncall | LParen exp RParen) {LBracket exp RBracket | Dot Name}"; got != want {
+		t.Errorf("prefixexp: want %q, got %q", want, got)
+	}
+}
+
+// shared returns a node that appears more than once in the grammar, or nil.
+func shared(grammar ebnf.Grammar) (dup ebnf.Expression) {
+	seen := make(map[ebnf.Expression]bool)
+	for _, prod := range grammar {
+		ebnf.Apply(prod.Expr, func(c *ebnf.Cursor) bool {
+			switch x := c.Node().(type) {
+			case ebnf.Alternative, ebnf.Sequence:
+				return true
+			default:
+				if seen[x] && dup == nil {
+					dup = x
+				}
+				seen[x] = true
+			}
+			return true
+		}, nil)
+	}
+	return dup
+}
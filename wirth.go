@@ -0,0 +1,156 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"fmt"
+	"github.com/mdhender/ebnf/tokens"
+	"github.com/mdhender/ebnf/wirth"
+)
+
+// FromWirth converts the productions of a wirth.Syntax to a Grammar.
+// The nodes share the tokens of the Syntax.
+//
+// A Term with a single Factor becomes that factor and an Expression with
+// a single Term becomes that term, just as Parse would build them.
+// A Factor holding an Expression becomes that expression without a Group.
+//
+// Errors are reported for productions defined more than once and
+// for factors that the wirth parser could not fill in.
+func FromWirth(syntax *wirth.Syntax) (Grammar, []error) {
+	var c wirthConverter
+	grammar := make(Grammar)
+	for _, production := range syntax.Productions {
+		if production.Identifier == nil {
+			c.error(production.Terminator, "production has no name")
+			continue
+		}
+		prod := &Production{
+			Name: &Name{tok: production.Identifier},
+			end:  production.Terminator,
+		}
+		if production.Expression != nil {
+			prod.Expr = c.expression(production.Expression, production.Identifier)
+		}
+		if err := grammar.define(prod); err != nil {
+			c.errors = append(c.errors, err)
+		}
+	}
+	return grammar, c.errors
+}
+
+// ToWirth converts the grammar to a wirth.Syntax with the productions in
+// source order. It is the inverse of FromWirth: the nodes share the tokens
+// of the Grammar, and an Alternative or Sequence nested directly in another
+// one is kept in the Expression field of a Factor.
+// Comments are not kept because wirth has no place for them.
+func ToWirth(grammar Grammar) *wirth.Syntax {
+	syntax := &wirth.Syntax{}
+	for _, prod := range Productions(grammar) {
+		production := &wirth.Production{Identifier: prod.Name.tok, Terminator: prod.end}
+		if prod.Expr != nil {
+			production.Expression = toWirthExpression(prod.Expr)
+		}
+		syntax.Productions = append(syntax.Productions, production)
+	}
+	return syntax
+}
+
+type wirthConverter struct {
+	errors errorList
+}
+
+func (c *wirthConverter) error(tok *tokens.Token, format string, args ...any) error {
+	err := fmt.Errorf("%d: %s", tok.Line(), fmt.Sprintf(format, args...))
+	c.errors = append(c.errors, err)
+	return err
+}
+
+// expression converts the expression.
+// tok is used to report errors when the expression is empty.
+func (c *wirthConverter) expression(x *wirth.Expression, tok *tokens.Token) Expression {
+	if len(x.Terms) == 0 {
+		return &Bad{tok: tok, err: c.error(tok, "empty expression")}
+	} else if len(x.Terms) == 1 {
+		return c.term(x.Terms[0], tok)
+	}
+	list := make(Alternative, 0, len(x.Terms))
+	for _, term := range x.Terms {
+		list = append(list, c.term(term, tok))
+	}
+	return list
+}
+
+func (c *wirthConverter) term(x *wirth.Term, tok *tokens.Token) Expression {
+	if len(x.Factors) == 0 {
+		return &Bad{tok: tok, err: c.error(tok, "empty term")}
+	} else if len(x.Factors) == 1 {
+		return c.factor(x.Factors[0], tok)
+	}
+	list := make(Sequence, 0, len(x.Factors))
+	for _, factor := range x.Factors {
+		list = append(list, c.factor(factor, tok))
+	}
+	return list
+}
+
+func (c *wirthConverter) factor(x *wirth.Factor, tok *tokens.Token) Expression {
+	switch {
+	case x.NonTerminal != nil:
+		return &Name{tok: x.NonTerminal}
+	case x.Terminal != nil:
+		return &Literal{tok: x.Terminal}
+	case x.Group != nil:
+		return &Group{tok: x.Group.Start, Body: c.expression(x.Group.Expression, x.Group.Start), end: x.Group.End}
+	case x.Option != nil:
+		return &Option{tok: x.Option.Start, Body: c.expression(x.Option.Expression, x.Option.Start), end: x.Option.End}
+	case x.Repetition != nil:
+		return &Repetition{tok: x.Repetition.Start, Body: c.expression(x.Repetition.Expression, x.Repetition.Start), end: x.Repetition.End}
+	case x.Expression != nil:
+		return c.expression(x.Expression, tok)
+	}
+	return &Bad{tok: tok, err: c.error(tok, "empty factor")}
+}
+
+func toWirthExpression(x Expression) *wirth.Expression {
+	if alt, ok := x.(Alternative); ok {
+		expression := &wirth.Expression{}
+		for _, e := range alt {
+			expression.Terms = append(expression.Terms, toWirthTerm(e))
+		}
+		return expression
+	}
+	return &wirth.Expression{Terms: []*wirth.Term{toWirthTerm(x)}}
+}
+
+func toWirthTerm(x Expression) *wirth.Term {
+	if seq, ok := x.(Sequence); ok {
+		term := &wirth.Term{}
+		for _, e := range seq {
+			term.Factors = append(term.Factors, toWirthFactor(e))
+		}
+		return term
+	}
+	return &wirth.Term{Factors: []*wirth.Factor{toWirthFactor(x)}}
+}
+
+func toWirthFactor(x Expression) *wirth.Factor {
+	switch x := x.(type) {
+	case Alternative, Sequence:
+		return &wirth.Factor{Expression: toWirthExpression(x)}
+	case *Name:
+		return &wirth.Factor{NonTerminal: x.tok}
+	case *Literal:
+		return &wirth.Factor{Terminal: x.tok}
+	case *Group:
+		return &wirth.Factor{Group: &wirth.Group{Start: x.tok, Expression: toWirthExpression(x.Body), End: x.end}}
+	case *Option:
+		return &wirth.Factor{Option: &wirth.Option{Start: x.tok, Expression: toWirthExpression(x.Body), End: x.end}}
+	case *Repetition:
+		return &wirth.Factor{Repetition: &wirth.Repetition{Start: x.tok, Expression: toWirthExpression(x.Body), End: x.end}}
+	}
+	// wirth has no node for Bad, so it becomes an empty factor
+	return &wirth.Factor{}
+}
@@ -4,7 +4,10 @@
 
 package wirth
 
-import "github.com/mdhender/ebnf/tokens"
+import (
+	"github.com/mdhender/ebnf/tokens"
+	"sort"
+)
 
 type Grammar struct {
 	Start       *tokens.Token
@@ -16,9 +19,26 @@ type Syntax struct {
 	Productions []*Production
 }
 
+// Syntax returns the productions of the grammar in the order they appear in the input.
+func (g *Grammar) Syntax() *Syntax {
+	syntax := &Syntax{}
+	for _, production := range g.Productions {
+		syntax.Productions = append(syntax.Productions, production)
+	}
+	sort.Slice(syntax.Productions, func(i, j int) bool {
+		a, b := syntax.Productions[i].Identifier.Pos, syntax.Productions[j].Identifier.Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return syntax
+}
+
 type Production struct {
 	Identifier *tokens.Token
 	Expression *Expression
+	Terminator *tokens.Token
 }
 
 type Expression struct {
@@ -117,9 +117,9 @@ func (p *parser) ntProduction() *Production {
 			p.next()
 		}
 	}
-	terminator, err := p.expect(tokens.TERMINATOR)
+	production.Terminator, err = p.expect(tokens.TERMINATOR)
 	if err != nil {
-		p.addError("%d:%d: production: %w", terminator.Line(), terminator.Column(), err)
+		p.addError("%d:%d: production: %w", production.Terminator.Line(), production.Terminator.Column(), err)
 	}
 	return production
 }
@@ -190,7 +190,7 @@ func (p *parser) ntGroup() *Group {
 	group := &Group{}
 	group.Start, err = p.expect(tokens.START_GROUP)
 	if err != nil {
-		p.addError("%d:%d: group: %w", group.Start.Line(), group.Start.Column())
+		p.addError("%d:%d: group: %w", group.Start.Line(), group.Start.Column(), err)
 	}
 	group.Expression = p.ntExpression()
 	group.End, err = p.expect(tokens.END_GROUP)
@@ -0,0 +1,80 @@
+// Copyright 2023 Michael D Henderson.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the COPYING file.
+
+package ebnf
+
+import (
+	"github.com/mdhender/ebnf/wirth"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWirth(t *testing.T) {
+	input, err := os.ReadFile("testdata/lua.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar, errs := Parse(input)
+	if errs != nil {
+		t.Fatalf("Parse failed: %v", errs)
+	}
+	wg, err := wirth.Parse(input)
+	if err != nil || wg.Errors != nil {
+		t.Fatalf("wirth.Parse failed: %v %v", err, wg.Errors)
+	}
+	syntax := wg.Syntax()
+
+	// wirth does not keep comments
+	for _, prod := range grammar {
+		prod.Doc, prod.LineComment, prod.Trailer = nil, nil, nil
+	}
+
+	fromWirth, errs := FromWirth(syntax)
+	if errs != nil {
+		t.Fatalf("FromWirth failed: %v", errs)
+	}
+	if len(fromWirth) != len(grammar) {
+		t.Errorf("FromWirth: want %d productions, got %d", len(grammar), len(fromWirth))
+	}
+	for name, prod := range grammar {
+		if !reflect.DeepEqual(prod, fromWirth[name]) {
+			t.Errorf("FromWirth: %s: productions differ", name)
+		}
+	}
+
+	toWirth := ToWirth(grammar)
+	if len(toWirth.Productions) != len(syntax.Productions) {
+		t.Fatalf("ToWirth: want %d productions, got %d", len(syntax.Productions), len(toWirth.Productions))
+	}
+	for i, production := range syntax.Productions {
+		if !reflect.DeepEqual(production, toWirth.Productions[i]) {
+			t.Errorf("ToWirth: %s: productions differ", string(production.Identifier.Text))
+		}
+	}
+
+	// both round trips give back the input
+	if roundTrip, _ := FromWirth(ToWirth(grammar)); !reflect.DeepEqual(grammar, roundTrip) {
+		t.Errorf("FromWirth(ToWirth(grammar)) differs from grammar")
+	}
+	if roundTrip := ToWirth(fromWirth); !reflect.DeepEqual(syntax, roundTrip) {
+		t.Errorf("ToWirth(FromWirth(syntax)) differs from syntax")
+	}
+}
+
+func TestWirthNested(t *testing.T) {
+	b := NewBuilder()
+	b.Prod("a").Expr(Alternative{b.T("A"), Sequence{b.T("B"), Sequence{b.T("C"), b.T("D")}}, Alternative{b.T("E"), b.N("a")}})
+	grammar, errs := b.Grammar()
+	if errs != nil {
+		t.Fatalf("Grammar failed: %v", errs)
+	}
+	roundTrip, errs := FromWirth(ToWirth(grammar))
+	if errs != nil {
+		t.Fatalf("FromWirth failed: %v", errs)
+	}
+	if !reflect.DeepEqual(grammar, roundTrip) {
+		t.Errorf("FromWirth(ToWirth(grammar)) differs from grammar")
+	}
+}